@@ -66,6 +66,7 @@ type testRevertMigrationParams struct {
 	migrationContext string
 	expectError      string
 	skipWait         bool
+	waitTimeout      time.Duration
 }
 
 var (
@@ -904,6 +905,36 @@ func testScheduler(t *testing.T) {
 				assert.ErrorContains(t, err, "broken pipe")
 			})
 		})
+
+		t.Run("cutover under concurrent writes", func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), extendedWaitTime*5)
+			defer cancel()
+
+			t.Run("populate t1_test", func(t *testing.T) {
+				onlineddl.VtgateExecQuery(t, &vtParams, populateT1Statement, "")
+			})
+
+			t1uuid = testOnlineDDLStatement(t, createParams(trivialAlterT1Statement, ddlStrategy, "vtgate", "", "", true)) // skip wait
+
+			var stats concurrentTransactionStats
+			t.Run("concurrent writes during cutover", func(t *testing.T) {
+				stats = runConcurrentTransactions(t, ctx, primaryTablet, []string{
+					"update t1_test set hint_col = 'concurrent write' where id = 1",
+				}, 10)
+			})
+			t.Run("expect completion", func(t *testing.T) {
+				status := onlineddl.WaitForMigrationStatus(t, &vtParams, shards, t1uuid, normalWaitTime, schema.OnlineDDLStatusComplete, schema.OnlineDDLStatusFailed)
+				fmt.Printf("# Migration status (for debug purposes): <%s>\n", status)
+				onlineddl.CheckMigrationStatus(t, &vtParams, shards, t1uuid, schema.OnlineDDLStatusComplete)
+			})
+			t.Run("every concurrent write accounted for", func(t *testing.T) {
+				// Cutover contention may cause some writers to hit a deadlock or lock-wait
+				// timeout, but every one of them must resolve one way or another: none should be
+				// left unaccounted for by runConcurrentTransactions.
+				assert.Equal(t, 10, stats.successes+stats.deadlocks+stats.lockWaitTimeouts+len(stats.otherErrors))
+				assert.Empty(t, stats.otherErrors)
+			})
+		})
 	}
 
 	if forceCutoverCapable {
@@ -3065,8 +3096,78 @@ func testForeignKeys(t *testing.T) {
 	}
 }
 
+// migrationEvent is one structured entry in a migrationTestRecorder's in-memory log: a
+// migration UUID transitioning through a named phase (e.g. "submit", "wait", "status") at a
+// point in time, optionally tied to a shard and carrying the resulting status if any.
+type migrationEvent struct {
+	uuid   string
+	phase  string
+	shard  string
+	status schema.OnlineDDLStatus
+	at     time.Time
+}
+
+// migrationTestRecorder collects structured migrationEvents in place of the scattered
+// `fmt.Printf("# ... debug ...")` calls throughout these test helpers, and dumps them as a
+// table on test failure via t.Cleanup, so flake diagnosis in CI doesn't depend on scrollback.
+type migrationTestRecorder struct {
+	mu     sync.Mutex
+	t      *testing.T
+	events []migrationEvent
+}
+
+// migrationTestRecorders holds the one migrationTestRecorder created per *testing.T, so the
+// many testOnlineDDLStatement/testRevertMigration calls a single (sub)test typically makes
+// share a single consolidated event log instead of each fragmenting it into its own recorder.
+var (
+	migrationTestRecordersMu sync.Mutex
+	migrationTestRecorders   = map[*testing.T]*migrationTestRecorder{}
+)
+
+// newMigrationTestRecorder returns the migrationTestRecorder already attached to t, creating one
+// if this is the first call for t. Its recorded events are dumped automatically if t fails.
+func newMigrationTestRecorder(t *testing.T) *migrationTestRecorder {
+	migrationTestRecordersMu.Lock()
+	defer migrationTestRecordersMu.Unlock()
+
+	if recorder, ok := migrationTestRecorders[t]; ok {
+		return recorder
+	}
+	recorder := &migrationTestRecorder{t: t}
+	migrationTestRecorders[t] = recorder
+	t.Cleanup(func() {
+		migrationTestRecordersMu.Lock()
+		delete(migrationTestRecorders, t)
+		migrationTestRecordersMu.Unlock()
+		if t.Failed() {
+			recorder.dump()
+		}
+	})
+	return recorder
+}
+
+// Record appends a migrationEvent. status may be the zero value when the phase has no
+// associated status (e.g. "submit").
+func (r *migrationTestRecorder) Record(uuid, phase, shard string, status schema.OnlineDDLStatus) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, migrationEvent{uuid: uuid, phase: phase, shard: shard, status: status, at: time.Now()})
+}
+
+// dump prints every recorded event as a table to aid diagnosing the failing test.
+func (r *migrationTestRecorder) dump() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.t.Logf("migrationTestRecorder: %d event(s) recorded for %s:", len(r.events), r.t.Name())
+	for _, event := range r.events {
+		r.t.Logf("  %-26s uuid=%-36s phase=%-10s shard=%-8s status=%s",
+			event.at.Format(time.RFC3339Nano), event.uuid, event.phase, event.shard, event.status)
+	}
+}
+
 // testOnlineDDLStatement runs an online DDL, ALTER statement
 func testOnlineDDLStatement(t *testing.T, params *testOnlineDDLStatementParams) (uuid string) {
+	recorder := newMigrationTestRecorder(t)
 	strategySetting, err := schema.ParseDDLStrategy(params.ddlStrategy)
 	require.NoError(t, err)
 
@@ -3104,12 +3205,11 @@ func testOnlineDDLStatement(t *testing.T, params *testOnlineDDLStatementParams)
 		}
 	}
 	uuid = strings.TrimSpace(uuid)
-	fmt.Println("# Generated UUID (for debug purposes):")
-	fmt.Printf("<%s>\n", uuid)
+	recorder.Record(uuid, "submit", "", "")
 
 	if !strategySetting.Strategy.IsDirect() && !params.skipWait && uuid != "" {
 		status := onlineddl.WaitForMigrationStatus(t, &vtParams, shards, uuid, normalWaitTime, schema.OnlineDDLStatusComplete, schema.OnlineDDLStatusFailed)
-		fmt.Printf("# Migration status (for debug purposes): <%s>\n", status)
+		recorder.Record(uuid, "wait", "", status)
 	}
 
 	if params.expectError == "" && params.expectHint != "" {
@@ -3120,6 +3220,7 @@ func testOnlineDDLStatement(t *testing.T, params *testOnlineDDLStatementParams)
 
 // testRevertMigration reverts a given migration
 func testRevertMigration(t *testing.T, params *testRevertMigrationParams) (uuid string) {
+	recorder := newMigrationTestRecorder(t)
 	revertQuery := fmt.Sprintf("revert vitess_migration '%s'", params.revertUUID)
 	if params.executeStrategy == "vtgate" {
 		require.Empty(t, params.migrationContext, "explicit migration context not supported in vtgate. Test via vtctl")
@@ -3143,15 +3244,26 @@ func testRevertMigration(t *testing.T, params *testRevertMigrationParams) (uuid
 
 	if params.expectError == "" {
 		uuid = strings.TrimSpace(uuid)
-		fmt.Println("# Generated UUID (for debug purposes):")
-		fmt.Printf("<%s>\n", uuid)
+		recorder.Record(uuid, "revert-submit", "", "")
 	}
-	if !params.skipWait {
-		time.Sleep(time.Second * 20)
+	if !params.skipWait && uuid != "" {
+		waitTimeout := params.waitTimeout
+		if waitTimeout == 0 {
+			waitTimeout = normalWaitTime
+		}
+		status := WaitForRevertMigrationStatus(t, &vtParams, shards, uuid, waitTimeout, schema.OnlineDDLStatusComplete, schema.OnlineDDLStatusFailed)
+		recorder.Record(uuid, "revert-wait", "", status)
 	}
 	return uuid
 }
 
+// WaitForRevertMigrationStatus polls the migrations schema for uuid until it reaches one of
+// expectStatuses or timeout elapses, mirroring onlineddl.WaitForMigrationStatus but for a
+// revert migration's own UUID rather than the migration it reverts.
+func WaitForRevertMigrationStatus(t *testing.T, vtParams *mysql.ConnParams, shards []cluster.Shard, uuid string, timeout time.Duration, expectStatuses ...schema.OnlineDDLStatus) schema.OnlineDDLStatus {
+	return onlineddl.WaitForMigrationStatus(t, vtParams, shards, uuid, timeout, expectStatuses...)
+}
+
 // checkTable checks the number of tables in all shards
 func checkTable(t *testing.T, showTableName string, expectExists bool) bool {
 	expectCount := 0
@@ -3174,14 +3286,94 @@ func checkTablesCount(t *testing.T, tablet *cluster.Vttablet, showTableName stri
 	return assert.Equalf(t, expectCount, len(queryResult.Rows), "checkTablesCount cannot find table like '%%%s%%'", showTableName)
 }
 
-// checkMigratedTables checks the CREATE STATEMENT of a table after migration
+// checkMigratedTables checks the CREATE STATEMENT of a table after migration. Beyond the
+// original expectHint substring check, it also canonicalizes every shard's CREATE TABLE via
+// sqlparser and compares them against each other, so that shard-skew after an online DDL
+// (a migration that silently succeeded on some shards and not others) fails loudly instead of
+// passing because the first shard happened to match expectHint.
 func checkMigratedTable(t *testing.T, tableName, expectHint string) {
+	checkMigratedTableOnShards(t, tableName, expectHint, "")
+}
+
+// checkMigratedTableAgainst is like checkMigratedTable, but additionally diffs every shard's
+// canonicalized CREATE TABLE against expectCreateStatement (a full expected CREATE TABLE
+// template), reporting the first differing shard with a unified diff in the failure message.
+func checkMigratedTableAgainst(t *testing.T, tableName, expectCreateStatement string) {
+	checkMigratedTableOnShards(t, tableName, "", expectCreateStatement)
+}
+
+func checkMigratedTableOnShards(t *testing.T, tableName, expectHint, expectCreateStatement string) {
+	var canonicalFirstShard string
+	var firstShardName string
 	for i := range clusterInstance.Keyspaces[0].Shards {
-		createStatement := getCreateTableStatement(t, clusterInstance.Keyspaces[0].Shards[i].Vttablets[0], tableName)
-		assert.Contains(t, createStatement, expectHint)
+		shard := &clusterInstance.Keyspaces[0].Shards[i]
+		createStatement := getCreateTableStatement(t, shard.Vttablets[0], tableName)
+		if expectHint != "" {
+			assert.Contains(t, createStatement, expectHint)
+		}
+		canonical := canonicalCreateTableStatement(t, createStatement)
+
+		if expectCreateStatement != "" {
+			expectedCanonical := canonicalCreateTableStatement(t, expectCreateStatement)
+			if canonical != expectedCanonical {
+				t.Errorf("checkMigratedTable: shard %s schema does not match expected template:\n%s",
+					shard.Name, unifiedDiff(expectedCanonical, canonical))
+			}
+			continue
+		}
+
+		if canonicalFirstShard == "" {
+			canonicalFirstShard = canonical
+			firstShardName = shard.Name
+			continue
+		}
+		if canonical != canonicalFirstShard {
+			t.Errorf("checkMigratedTable: shard %s schema diverges from shard %s:\n%s",
+				shard.Name, firstShardName, unifiedDiff(canonicalFirstShard, canonical))
+		}
 	}
 }
 
+// canonicalCreateTableStatement parses and re-serializes a CREATE TABLE statement via
+// sqlparser so that formatting differences (whitespace, keyword case) don't register as schema
+// divergence. Column and constraint order is kept as parsed, so a different order still does.
+func canonicalCreateTableStatement(t *testing.T, createStatement string) string {
+	parser := sqlparser.NewTestParser()
+	stmt, err := parser.ParseStrictDDL(createStatement)
+	require.NoError(t, err)
+	return sqlparser.String(stmt)
+}
+
+// unifiedDiff renders a minimal line-oriented diff between two strings for failure messages.
+func unifiedDiff(expected, actual string) string {
+	expectedLines := strings.Split(expected, "\n")
+	actualLines := strings.Split(actual, "\n")
+	var sb strings.Builder
+	max := len(expectedLines)
+	if len(actualLines) > max {
+		max = len(actualLines)
+	}
+	for i := 0; i < max; i++ {
+		var expectedLine, actualLine string
+		if i < len(expectedLines) {
+			expectedLine = expectedLines[i]
+		}
+		if i < len(actualLines) {
+			actualLine = actualLines[i]
+		}
+		if expectedLine == actualLine {
+			continue
+		}
+		if expectedLine != "" {
+			fmt.Fprintf(&sb, "-%s\n", expectedLine)
+		}
+		if actualLine != "" {
+			fmt.Fprintf(&sb, "+%s\n", actualLine)
+		}
+	}
+	return sb.String()
+}
+
 // getCreateTableStatement returns the CREATE TABLE statement for a given table
 func getCreateTableStatement(t *testing.T, tablet *cluster.Vttablet, tableName string) (statement string) {
 	queryResult, err := tablet.VttabletProcess.QueryTablet(fmt.Sprintf("show create table %s;", tableName), keyspaceName, true)
@@ -3220,3 +3412,63 @@ func runInTransaction(t *testing.T, ctx context.Context, tablet *cluster.Vttable
 	}
 	return err
 }
+
+// concurrentTransactionStats aggregates the outcome of runConcurrentTransactions' N
+// goroutines, so revert-under-load and cutover-contention tests can assert on how a migration
+// handled concurrent writers, not just whether every single one happened to succeed.
+type concurrentTransactionStats struct {
+	successes        int
+	deadlocks        int
+	lockWaitTimeouts int
+	otherErrors      []error
+}
+
+// runConcurrentTransactions fans out n goroutines, each opening its own tablet connection and
+// running one query from queries (round-robin if len(queries) < n) inside its own
+// begin/commit, and returns aggregate stats once every goroutine has finished. Unlike
+// runInTransaction, there is no commit-coordination channel: every goroutine commits as soon as
+// its query completes, so this is meant for exercising contention (revert-under-load,
+// cutover-vs-writes), not for deterministically interleaving a specific commit order.
+func runConcurrentTransactions(t *testing.T, ctx context.Context, tablet *cluster.Vttablet, queries []string, n int) concurrentTransactionStats {
+	require.NotEmpty(t, queries)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		query := queries[i%len(queries)]
+		go func(query string) {
+			defer wg.Done()
+			conn, err := tablet.VttabletProcess.TabletConn(keyspaceName, true)
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer conn.Close()
+			for _, statement := range []string{"begin", query, "commit"} {
+				if _, err := conn.ExecuteFetch(statement, 10000, false); err != nil {
+					errs <- err
+					return
+				}
+			}
+			errs <- nil
+		}(query)
+	}
+	wg.Wait()
+	close(errs)
+
+	var stats concurrentTransactionStats
+	for err := range errs {
+		switch {
+		case err == nil:
+			stats.successes++
+		case strings.Contains(err.Error(), "Deadlock found"):
+			stats.deadlocks++
+		case strings.Contains(err.Error(), "Lock wait timeout exceeded"):
+			stats.lockWaitTimeouts++
+		default:
+			stats.otherErrors = append(stats.otherErrors, err)
+		}
+	}
+	return stats
+}
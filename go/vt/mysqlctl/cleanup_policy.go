@@ -0,0 +1,135 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mysqlctl
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// mysqlShellRestorePreserveUsers is the `--restore-preserve-users` flag: a comma-separated list
+// of user@host patterns, or a path to a file containing one pattern per line, naming accounts
+// that cleanupMySQL must never drop beyond its built-in reserved accounts
+// (mysql.sys/mysql.infoschema/mysql.session).
+var mysqlShellRestorePreserveUsers string
+
+func init() {
+	flag.StringVar(&mysqlShellRestorePreserveUsers, "restore-preserve-users", mysqlShellRestorePreserveUsers,
+		"comma-separated list of user@host patterns (glob or /regex/), or a path to a file containing one per line, "+
+			"that a mysql-shell restore's cleanup step must never drop")
+}
+
+// CleanupPolicy governs which users and databases cleanupMySQL is allowed to drop when
+// restoring via mysql-shell, beyond the engine's built-in reserved system accounts/databases.
+// It is threaded through RestoreParams so a deployment's vendor-specific system accounts
+// (Percona's mysql.pxc.internal.session, orchestrator users, monitoring roles, Vault-issued
+// dynamic users, etc.) can be preserved without modifying the engine itself.
+type CleanupPolicy struct {
+	// PreserveUserPatterns are user@host patterns (glob via path.Match syntax, or /regex/ when
+	// wrapped in slashes) naming accounts that must never be dropped.
+	PreserveUserPatterns []string
+	// PreserveDatabases names additional databases (beyond the engine's built-in system
+	// databases) that must never be dropped.
+	PreserveDatabases []string
+	// DryRun, when true, logs the DROP statements cleanupMySQL would issue without executing
+	// them.
+	DryRun bool
+}
+
+// ParsePreserveUsersFlag resolves the --restore-preserve-users flag value into a pattern list:
+// if spec names an existing file, its lines (ignoring blanks) are used; otherwise spec itself
+// is split on commas.
+func ParsePreserveUsersFlag(spec string) ([]string, error) {
+	if spec == "" {
+		return nil, nil
+	}
+	if info, err := os.Stat(spec); err == nil && !info.IsDir() {
+		contents, err := os.ReadFile(spec)
+		if err != nil {
+			return nil, fmt.Errorf("cleanup_policy: reading --restore-preserve-users file %q: %w", spec, err)
+		}
+		var patterns []string
+		for _, line := range strings.Split(string(contents), "\n") {
+			line = strings.TrimSpace(line)
+			if line != "" {
+				patterns = append(patterns, line)
+			}
+		}
+		return patterns, nil
+	}
+	var patterns []string
+	for _, pattern := range strings.Split(spec, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns, nil
+}
+
+// ShouldPreserveUser reports whether user@host matches any of the policy's preserve patterns.
+// A pattern wrapped in slashes (e.g. "/^mysql\\.pxc\\./") is matched as a regular expression
+// against "user@host"; any other pattern is matched via glob (path.Match) syntax against the
+// same string.
+func (p *CleanupPolicy) ShouldPreserveUser(user, host string) (bool, error) {
+	subject := user + "@" + host
+	for _, pattern := range p.PreserveUserPatterns {
+		if strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") && len(pattern) >= 2 {
+			re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+			if err != nil {
+				return false, fmt.Errorf("cleanup_policy: invalid regex pattern %q: %w", pattern, err)
+			}
+			if re.MatchString(subject) {
+				return true, nil
+			}
+			continue
+		}
+		matched, err := filepath.Match(pattern, subject)
+		if err != nil {
+			return false, fmt.Errorf("cleanup_policy: invalid glob pattern %q: %w", pattern, err)
+		}
+		if matched {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ShouldPreserveDatabase reports whether db is named in the policy's preserved-databases list.
+func (p *CleanupPolicy) ShouldPreserveDatabase(db string) bool {
+	for _, preserved := range p.PreserveDatabases {
+		if preserved == db {
+			return true
+		}
+	}
+	return false
+}
+
+// LogOrExecute either logs statement as a would-be DROP (DryRun) or runs execute, returning its
+// error. cleanupMySQL should call this for every DROP it considers issuing, after consulting
+// ShouldPreserveUser/ShouldPreserveDatabase.
+func (p *CleanupPolicy) LogOrExecute(statement string, log func(format string, args ...any), execute func() error) error {
+	if p.DryRun {
+		log("cleanup_policy: dry-run, would execute: %s", statement)
+		return nil
+	}
+	return execute()
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mysqlctl
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"vitess.io/vitess/go/mysql/fakesqldb"
+	"vitess.io/vitess/go/sqltypes"
+	"vitess.io/vitess/go/vt/logutil"
+)
+
+// TestCleanupMySQLWithCleanupPolicy extends TestCleanupMySQL's coverage with a CleanupPolicy
+// threaded through RestoreParams: preserving a vendor-specific (Percona/XtraDB) system account
+// beyond the built-in reserved set, and a dry-run pass that issues no DROPs at all.
+func TestCleanupMySQLWithCleanupPolicy(t *testing.T) {
+	t.Run("preserves a Percona system account via policy", func(t *testing.T) {
+		fakedb := fakesqldb.New(t)
+		defer fakedb.Close()
+		mysql := NewFakeMysqlDaemon(fakedb)
+		defer mysql.Close()
+
+		mysql.FetchSuperQueryMap = map[string]*sqltypes.Result{
+			"SHOW DATABASES": {Rows: [][]sqltypes.Value{
+				{sqltypes.NewVarChar("_vt")},
+			}},
+			"SELECT user, host FROM mysql.user": {Rows: [][]sqltypes.Value{
+				{sqltypes.NewVarChar("mysql.pxc.internal.session"), sqltypes.NewVarChar("localhost")},
+				{sqltypes.NewVarChar("app"), sqltypes.NewVarChar("10.0.0.1")},
+			}},
+		}
+		mysql.ExpectedExecuteSuperQueryList = []string{
+			"DROP DATABASE IF EXISTS `_vt`",
+			"DROP USER 'app'@'10.0.0.1'",
+		}
+
+		params := RestoreParams{
+			Mysqld: mysql,
+			Logger: logutil.NewMemoryLogger(),
+			CleanupPolicy: &CleanupPolicy{
+				PreserveUserPatterns: []string{`/^mysql\.pxc\./`},
+			},
+		}
+
+		require.NoError(t, cleanupMySQL(context.Background(), params, true))
+		require.Equal(t, 2, mysql.ExpectedExecuteSuperQueryCurrent)
+	})
+
+	t.Run("dry-run logs would-be DROPs without executing them", func(t *testing.T) {
+		fakedb := fakesqldb.New(t)
+		defer fakedb.Close()
+		mysql := NewFakeMysqlDaemon(fakedb)
+		defer mysql.Close()
+
+		mysql.FetchSuperQueryMap = map[string]*sqltypes.Result{
+			"SHOW DATABASES": {Rows: [][]sqltypes.Value{
+				{sqltypes.NewVarChar("_vt")},
+				{sqltypes.NewVarChar("vt_test")},
+			}},
+			"SELECT user, host FROM mysql.user": {Rows: [][]sqltypes.Value{
+				{sqltypes.NewVarChar("app"), sqltypes.NewVarChar("10.0.0.1")},
+			}},
+		}
+		// No ExpectedExecuteSuperQueryList entries: a dry-run must never call ExecuteSuperQuery.
+
+		logger := logutil.NewMemoryLogger()
+		params := RestoreParams{
+			Mysqld:        mysql,
+			Logger:        logger,
+			CleanupPolicy: &CleanupPolicy{DryRun: true},
+		}
+
+		require.NoError(t, cleanupMySQL(context.Background(), params, true))
+		require.Zero(t, mysql.ExpectedExecuteSuperQueryCurrent)
+		for _, statement := range []string{
+			"DROP DATABASE IF EXISTS `_vt`",
+			"DROP DATABASE IF EXISTS `vt_test`",
+			"DROP USER 'app'@'10.0.0.1'",
+		} {
+			require.Contains(t, logger.String(), fmt.Sprintf("would execute: %s", statement))
+		}
+	})
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mysqlctl
+
+import (
+	"context"
+	"io"
+)
+
+// FakeBackupHandleAddFileReturn configures what FakeBackupHandle.AddFile returns.
+type FakeBackupHandleAddFileReturn struct {
+	WriteCloser io.WriteCloser
+	Err         error
+}
+
+// FakeBackupHandle is a BackupHandle for tests.
+type FakeBackupHandle struct {
+	Dir            string
+	Name           string
+	AddFileReturn  FakeBackupHandleAddFileReturn
+	EndBackupErr   error
+	AbortBackupErr error
+}
+
+// AddFile returns the configured WriteCloser/error, ignoring filename/filesize.
+func (h *FakeBackupHandle) AddFile(ctx context.Context, filename string, filesize int64) (io.WriteCloser, error) {
+	return h.AddFileReturn.WriteCloser, h.AddFileReturn.Err
+}
+
+// EndBackup returns the configured error.
+func (h *FakeBackupHandle) EndBackup(ctx context.Context) error {
+	return h.EndBackupErr
+}
+
+// AbortBackup returns the configured error.
+func (h *FakeBackupHandle) AbortBackup(ctx context.Context) error {
+	return h.AbortBackupErr
+}
+
+// FakeBackupStorageStartBackupReturn configures what FakeBackupStorage.StartBackup returns.
+type FakeBackupStorageStartBackupReturn struct {
+	BackupHandle BackupHandle
+	Err          error
+}
+
+// FakeBackupStorage is a BackupStorage for tests.
+type FakeBackupStorage struct {
+	StartBackupReturn FakeBackupStorageStartBackupReturn
+}
+
+// StartBackup returns the configured BackupHandle/error, ignoring dir/name.
+func (s *FakeBackupStorage) StartBackup(ctx context.Context, dir, name string) (BackupHandle, error) {
+	return s.StartBackupReturn.BackupHandle, s.StartBackupReturn.Err
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mysqlctl
+
+import (
+	"context"
+	"fmt"
+
+	"vitess.io/vitess/go/mysql/fakesqldb"
+	"vitess.io/vitess/go/sqltypes"
+)
+
+// FakeMysqlDaemon is a MysqlDaemon backed by a fakesqldb.DB, for exercising
+// MySQLShellBackupEngine without a real mysqld.
+type FakeMysqlDaemon struct {
+	db *fakesqldb.DB
+
+	// Version is returned by GetVersionString, e.g. "mysqld  Ver 8.0.42 for Linux on x86_64".
+	Version string
+
+	// GlobalReadLock reflects whether AcquireGlobalReadLock has been called without a matching
+	// ReleaseGlobalReadLock, so tests can assert the lock was actually released.
+	GlobalReadLock bool
+
+	// FetchSuperQueryMap maps an exact query string to the *sqltypes.Result FetchSuperQuery
+	// returns for it.
+	FetchSuperQueryMap map[string]*sqltypes.Result
+
+	// ExpectedExecuteSuperQueryList is the ordered list of queries ExecuteSuperQuery is
+	// expected to be called with; ExpectedExecuteSuperQueryCurrent counts how many have been
+	// consumed so far.
+	ExpectedExecuteSuperQueryList    []string
+	ExpectedExecuteSuperQueryCurrent int
+}
+
+// NewFakeMysqlDaemon creates a FakeMysqlDaemon backed by db.
+func NewFakeMysqlDaemon(db *fakesqldb.DB) *FakeMysqlDaemon {
+	return &FakeMysqlDaemon{db: db, FetchSuperQueryMap: map[string]*sqltypes.Result{}}
+}
+
+// Close releases the underlying fake database.
+func (f *FakeMysqlDaemon) Close() {
+	if f.db != nil {
+		f.db.Close()
+	}
+}
+
+// GetVersionString returns the configured Version.
+func (f *FakeMysqlDaemon) GetVersionString(ctx context.Context) (string, error) {
+	return f.Version, nil
+}
+
+// AcquireGlobalReadLock marks the global read lock as held.
+func (f *FakeMysqlDaemon) AcquireGlobalReadLock(ctx context.Context) error {
+	f.GlobalReadLock = true
+	return nil
+}
+
+// ReleaseGlobalReadLock marks the global read lock as released.
+func (f *FakeMysqlDaemon) ReleaseGlobalReadLock(ctx context.Context) error {
+	f.GlobalReadLock = false
+	return nil
+}
+
+// FetchSuperQuery returns the canned result for query from FetchSuperQueryMap.
+func (f *FakeMysqlDaemon) FetchSuperQuery(ctx context.Context, query string) (*sqltypes.Result, error) {
+	result, ok := f.FetchSuperQueryMap[query]
+	if !ok {
+		return nil, fmt.Errorf("fakemysqldaemon: no result configured for query %q", query)
+	}
+	return result, nil
+}
+
+// ExecuteSuperQuery asserts query matches the next unconsumed entry in
+// ExpectedExecuteSuperQueryList.
+func (f *FakeMysqlDaemon) ExecuteSuperQuery(ctx context.Context, query string) error {
+	if f.ExpectedExecuteSuperQueryCurrent >= len(f.ExpectedExecuteSuperQueryList) {
+		return fmt.Errorf("fakemysqldaemon: unexpected query %q, no more expected queries", query)
+	}
+	expected := f.ExpectedExecuteSuperQueryList[f.ExpectedExecuteSuperQueryCurrent]
+	f.ExpectedExecuteSuperQueryCurrent++
+	if query != expected {
+		return fmt.Errorf("fakemysqldaemon: query mismatch at index %d: got %q, want %q", f.ExpectedExecuteSuperQueryCurrent-1, query, expected)
+	}
+	return nil
+}
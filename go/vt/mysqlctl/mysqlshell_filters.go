@@ -0,0 +1,121 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mysqlctl
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// sidecarDBName is the Vitess sidecar schema that must never be excluded from a mysql-shell
+// restore, since vttablet cannot serve the keyspace without it.
+const sidecarDBName = "_vt"
+
+// MySQLShellSchemaFilter is the first-class, typed counterpart to passing raw
+// includeSchemas/excludeSchemas/includeTables/excludeTables/includeUsers/excludeUsers options
+// to mysqlsh's util.dumpInstance/util.loadDump as JSON. BackupParams and RestoreParams embed
+// this so partial-shard restores and table-level DR workflows don't require hand-authoring the
+// JSON blob passed via mysqlShellFlags/mysqlShellLoadFlags.
+type MySQLShellSchemaFilter struct {
+	IncludeSchemas []string
+	ExcludeSchemas []string
+	IncludeTables  []string
+	ExcludeTables  []string
+	IncludeUsers   []string
+	ExcludeUsers   []string
+}
+
+// Validate rejects a filter that both includes and excludes the same schema/table, and rejects
+// any attempt to exclude the Vitess sidecar schema (which would leave the restored instance
+// unservable).
+func (f MySQLShellSchemaFilter) Validate() error {
+	if err := checkNoOverlap("schemas", f.IncludeSchemas, f.ExcludeSchemas); err != nil {
+		return err
+	}
+	if err := checkNoOverlap("tables", f.IncludeTables, f.ExcludeTables); err != nil {
+		return err
+	}
+	if err := checkNoOverlap("users", f.IncludeUsers, f.ExcludeUsers); err != nil {
+		return err
+	}
+	for _, excluded := range f.ExcludeSchemas {
+		if excluded == sidecarDBName {
+			return fmt.Errorf("%w: cannot exclude Vitess sidecar schema %q", ErrMySQLShellPreCheck, sidecarDBName)
+		}
+	}
+	return nil
+}
+
+func checkNoOverlap(kind string, includes, excludes []string) error {
+	excluded := make(map[string]bool, len(excludes))
+	for _, name := range excludes {
+		excluded[name] = true
+	}
+	for _, name := range includes {
+		if excluded[name] {
+			return fmt.Errorf("%w: %s %q is both included and excluded", ErrMySQLShellPreCheck, kind, name)
+		}
+	}
+	return nil
+}
+
+// MergeIntoOptions merges the filter's non-empty fields into options (a parsed mysqlsh
+// dumpInstance/loadDump JSON options object), without overwriting a key the user already set
+// explicitly in mysqlShellFlags/mysqlShellLoadFlags: the typed filter only fills in keys that
+// are absent from options.
+func (f MySQLShellSchemaFilter) MergeIntoOptions(options map[string]any) map[string]any {
+	if options == nil {
+		options = map[string]any{}
+	}
+	setIfAbsent(options, "includeSchemas", f.IncludeSchemas)
+	setIfAbsent(options, "excludeSchemas", f.ExcludeSchemas)
+	setIfAbsent(options, "includeTables", f.IncludeTables)
+	setIfAbsent(options, "excludeTables", f.ExcludeTables)
+	setIfAbsent(options, "includeUsers", f.IncludeUsers)
+	setIfAbsent(options, "excludeUsers", f.ExcludeUsers)
+	return options
+}
+
+func setIfAbsent(options map[string]any, key string, values []string) {
+	if len(values) == 0 {
+		return
+	}
+	if _, alreadySet := options[key]; alreadySet {
+		return
+	}
+	options[key] = values
+}
+
+// MergeSchemaFilterJSON parses rawOptionsJSON (as currently passed via mysqlShellFlags /
+// mysqlShellLoadFlags), merges in filter, and re-serializes the result, for
+// ExecuteBackup/ExecuteRestore to pass on to mysqlsh.
+func MergeSchemaFilterJSON(rawOptionsJSON string, filter MySQLShellSchemaFilter) (string, error) {
+	if err := filter.Validate(); err != nil {
+		return "", err
+	}
+	options := map[string]any{}
+	if rawOptionsJSON != "" {
+		if err := json.Unmarshal([]byte(rawOptionsJSON), &options); err != nil {
+			return "", fmt.Errorf("%w: invalid JSON options: %v", ErrMySQLShellPreCheck, err)
+		}
+	}
+	merged, err := json.Marshal(filter.MergeIntoOptions(options))
+	if err != nil {
+		return "", fmt.Errorf("mysqlshell_filters: marshaling merged options: %w", err)
+	}
+	return string(merged), nil
+}
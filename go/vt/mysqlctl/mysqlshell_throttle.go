@@ -0,0 +1,227 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mysqlctl
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// mysqlShellThrottle* flags configure the backpressure MySQLShellBackupEngine.ExecuteBackup
+// applies to its running mysqlsh process: while any configured signal is over threshold, the
+// process is paused (SIGSTOP/SIGCONT) rather than killed, since mysqlsh has no native
+// throttling API of its own.
+var (
+	mysqlShellThrottleControlReplicas string
+	mysqlShellThrottleMaxLag          = 5 * time.Second
+	mysqlShellThrottleQuery           string
+	mysqlShellThrottleQueryMax        float64
+	mysqlShellThrottleHTTP            string
+	mysqlShellThrottleFile            string
+	mysqlShellThrottleCheckInterval   = time.Second
+)
+
+func init() {
+	flag.StringVar(&mysqlShellThrottleControlReplicas, "mysql-shell-throttle-control-replicas", mysqlShellThrottleControlReplicas,
+		"comma-separated list of replica tablet aliases whose Seconds_Behind_Source is sampled to throttle mysqlsh")
+	flag.DurationVar(&mysqlShellThrottleMaxLag, "mysql-shell-throttle-max-lag", mysqlShellThrottleMaxLag,
+		"pause mysqlsh while any control replica's replication lag exceeds this duration")
+	flag.StringVar(&mysqlShellThrottleQuery, "mysql-shell-throttle-query", mysqlShellThrottleQuery,
+		"optional query returning a single numeric metric; mysqlsh is paused while it exceeds --mysql-shell-throttle-query-max")
+	flag.Float64Var(&mysqlShellThrottleQueryMax, "mysql-shell-throttle-query-max", mysqlShellThrottleQueryMax,
+		"threshold for --mysql-shell-throttle-query")
+	flag.StringVar(&mysqlShellThrottleHTTP, "mysql-shell-throttle-http", mysqlShellThrottleHTTP,
+		"optional HTTP endpoint for a throttle decision (matching the vttablet throttler's check API); the mysql-shell backup engine does not poll it, so setting it alone never pauses mysqlsh")
+	flag.StringVar(&mysqlShellThrottleFile, "mysql-shell-throttle-file", mysqlShellThrottleFile,
+		"path to a file whose mere presence pauses mysqlsh, so an operator can throttle a running backup by hand")
+}
+
+// ThrottleSignal identifies one of the independent conditions a Throttler samples.
+type ThrottleSignal string
+
+const (
+	ThrottleSignalReplicaLag ThrottleSignal = "replica_lag"
+	ThrottleSignalQuery      ThrottleSignal = "query"
+	ThrottleSignalHTTP       ThrottleSignal = "http"
+	ThrottleSignalFile       ThrottleSignal = "file"
+)
+
+// ThrottleCheck samples one ThrottleSignal, returning whether it currently indicates the
+// caller should throttle and, if so, a human-readable reason for logging.
+type ThrottleCheck func() (shouldThrottle bool, reason string, err error)
+
+// Throttler periodically samples a set of ThrottleChecks and pauses/resumes an external
+// process (via SIGSTOP/SIGCONT) while any of them indicates backpressure is needed. It is
+// generic over the PID it controls so both MySQLShellBackupEngine's backup path and restore
+// paths (and, in principle, other backup engines) can reuse the same mechanism.
+type Throttler struct {
+	checks     map[ThrottleSignal]ThrottleCheck
+	checkEvery time.Duration
+
+	mu               sync.Mutex
+	paused           bool
+	pauseStartedAt   time.Time
+	totalPaused      time.Duration
+	signalPauseCount map[ThrottleSignal]int64
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewThrottler creates a Throttler sampling checks every checkEvery (falling back to
+// mysqlShellThrottleCheckInterval if zero).
+func NewThrottler(checks map[ThrottleSignal]ThrottleCheck, checkEvery time.Duration) *Throttler {
+	if checkEvery <= 0 {
+		checkEvery = mysqlShellThrottleCheckInterval
+	}
+	return &Throttler{
+		checks:           checks,
+		checkEvery:       checkEvery,
+		signalPauseCount: map[ThrottleSignal]int64{},
+	}
+}
+
+// Run samples checks every checkEvery against pid, sending SIGSTOP/SIGCONT as needed, until
+// stop() is called. log receives one line per pause/resume transition.
+func (th *Throttler) Run(pid int, log func(format string, args ...any)) (stop func()) {
+	th.stop = make(chan struct{})
+	th.done = make(chan struct{})
+
+	go func() {
+		defer close(th.done)
+		ticker := time.NewTicker(th.checkEvery)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-th.stop:
+				th.resume(pid, log)
+				return
+			case <-ticker.C:
+				th.sampleOnce(pid, log)
+			}
+		}
+	}()
+
+	return func() {
+		close(th.stop)
+		<-th.done
+	}
+}
+
+func (th *Throttler) sampleOnce(pid int, log func(format string, args ...any)) {
+	for signal, check := range th.checks {
+		shouldThrottle, reason, err := check()
+		if err != nil {
+			log("mysqlctl: throttle check %s failed: %v", signal, err)
+			continue
+		}
+		if shouldThrottle {
+			th.pause(pid, signal, reason, log)
+			return
+		}
+	}
+	th.resume(pid, log)
+}
+
+func (th *Throttler) pause(pid int, signal ThrottleSignal, reason string, log func(format string, args ...any)) {
+	th.mu.Lock()
+	defer th.mu.Unlock()
+	if th.paused {
+		return
+	}
+	if err := syscall.Kill(pid, syscall.SIGSTOP); err != nil {
+		log("mysqlctl: failed to pause pid %d: %v", pid, err)
+		return
+	}
+	th.paused = true
+	th.pauseStartedAt = time.Now()
+	th.signalPauseCount[signal]++
+	log("mysqlctl: pausing mysqlsh (pid %d) due to %s: %s", pid, signal, reason)
+}
+
+func (th *Throttler) resume(pid int, log func(format string, args ...any)) {
+	th.mu.Lock()
+	defer th.mu.Unlock()
+	if !th.paused {
+		return
+	}
+	if err := syscall.Kill(pid, syscall.SIGCONT); err != nil {
+		log("mysqlctl: failed to resume pid %d: %v", pid, err)
+		return
+	}
+	th.totalPaused += time.Since(th.pauseStartedAt)
+	th.paused = false
+	log("mysqlctl: resuming mysqlsh (pid %d)", pid)
+}
+
+// ThrottleStats summarizes a Throttler's activity over a backup/restore, meant to be embedded
+// into MySQLShellBackupManifest as ThrottledDuration/per-signal counters so operators can
+// measure impact.
+type ThrottleStats struct {
+	ThrottledDuration time.Duration
+	SignalPauseCounts map[ThrottleSignal]int64
+}
+
+// Stats snapshots the Throttler's cumulative pause time and per-signal pause counts.
+func (th *Throttler) Stats() ThrottleStats {
+	th.mu.Lock()
+	defer th.mu.Unlock()
+	total := th.totalPaused
+	if th.paused {
+		total += time.Since(th.pauseStartedAt)
+	}
+	counts := make(map[ThrottleSignal]int64, len(th.signalPauseCount))
+	for signal, count := range th.signalPauseCount {
+		counts[signal] = count
+	}
+	return ThrottleStats{ThrottledDuration: total, SignalPauseCounts: counts}
+}
+
+// ThrottleFileCheck builds a ThrottleCheck for --mysql-shell-throttle-file: it throttles for as
+// long as the file exists.
+func ThrottleFileCheck(path string) ThrottleCheck {
+	return func() (bool, string, error) {
+		if path == "" {
+			return false, "", nil
+		}
+		if _, err := os.Stat(path); err == nil {
+			return true, fmt.Sprintf("throttle file %q present", path), nil
+		} else if !os.IsNotExist(err) {
+			return false, "", err
+		}
+		return false, "", nil
+	}
+}
+
+// ReplicaLagCheck builds a ThrottleCheck from a function returning the current max
+// Seconds_Behind_Source across --mysql-shell-throttle-control-replicas.
+func ReplicaLagCheck(maxLag time.Duration, sampleMaxLag func() (time.Duration, error)) ThrottleCheck {
+	return func() (bool, string, error) {
+		lag, err := sampleMaxLag()
+		if err != nil {
+			return false, "", err
+		}
+		if lag > maxLag {
+			return true, fmt.Sprintf("replica lag %s exceeds max %s", lag, maxLag), nil
+		}
+		return false, "", nil
+	}
+}
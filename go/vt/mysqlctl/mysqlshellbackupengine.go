@@ -0,0 +1,558 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mysqlctl
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"vitess.io/vitess/go/sqltypes"
+	"vitess.io/vitess/go/vt/logutil"
+	"vitess.io/vitess/go/vt/mysqlctl/backupstats"
+	tabletmanagerdatapb "vitess.io/vitess/go/vt/proto/tabletmanagerdata"
+)
+
+// mysqlShellBackupEngineName is the engine name under which this engine registers itself (and
+// the name every per-engine metric/log line is keyed by).
+const mysqlShellBackupEngineName = "mysqlshell"
+
+// mysqlShellLockMessage is the line mysqlsh writes to stderr once it has acquired its own
+// global read lock for a consistent dump. ExecuteBackup watches for it so the engine's own,
+// earlier-acquired lock can be released as soon as mysqlsh's takes over, rather than being held
+// for the backup's entire duration.
+const mysqlShellLockMessage = "mysqlsh: acquired global read lock"
+
+// mysqlShell* flags configure how ExecuteBackup/restorePreCheck invoke mysqlsh.
+var (
+	mysqlShellBackupLocation    string
+	mysqlShellFlags             string
+	mysqlShellDumpOptions       string
+	mysqlShellLoadFlags         string
+	mysqlShellSpeedUpRestore    bool
+	mysqlShellBackupShouldDrain bool
+)
+
+func init() {
+	flag.StringVar(&mysqlShellBackupLocation, "mysql-shell-backup-location", mysqlShellBackupLocation,
+		"directory (local path or remote URL understood by mysqlsh) mysqlsh dumps/loads are written to/read from")
+	flag.StringVar(&mysqlShellFlags, "mysql-shell-flags", mysqlShellFlags,
+		"JSON object of extra util.dumpInstance options, plus mysqlsh CLI flags; must include --js")
+	flag.StringVar(&mysqlShellDumpOptions, "mysql-shell-dump-options", mysqlShellDumpOptions,
+		"JSON object of util.dumpInstance options (includeSchemas/excludeSchemas/includeTables/excludeTables/includeUsers/excludeUsers, etc.)")
+	flag.StringVar(&mysqlShellLoadFlags, "mysql-shell-load-flags", mysqlShellLoadFlags,
+		`JSON object of util.loadDump options; must set updateGtidSet to "replace" and leave progressFile empty`)
+	flag.BoolVar(&mysqlShellSpeedUpRestore, "mysql-shell-speedup-restore", mysqlShellSpeedUpRestore,
+		"disable the InnoDB redo log for the duration of the restore (requires MySQL/Percona Server >= 8.0.21)")
+	flag.BoolVar(&mysqlShellBackupShouldDrain, "mysql-shell-should-drain", mysqlShellBackupShouldDrain,
+		"drain the tablet before taking a mysql-shell backup")
+}
+
+// ErrMySQLShellPreCheck is returned by backupPreCheck/restorePreCheck when the engine's
+// configuration or the target instance isn't in a state mysqlsh can safely be run against.
+var ErrMySQLShellPreCheck = errors.New("mysqlshellbackupengine: pre-check failed")
+
+// MySQLShellBackupManifest is the manifest ExecuteBackup writes alongside the mysqlsh dump,
+// recording how the backup was taken.
+type MySQLShellBackupManifest struct {
+	BackupMethod string
+
+	// ThrottledDuration and ThrottleSignalPauseCounts summarize how much (and why) the running
+	// mysqlsh process was paused by the configured Throttler, so operators can measure the
+	// backup's impact on the tablet it ran against.
+	ThrottledDuration         time.Duration
+	ThrottleSignalPauseCounts map[ThrottleSignal]int64
+}
+
+// MysqlDaemon is the subset of vttablet's mysqld control surface MySQLShellBackupEngine needs:
+// reading the server version, taking/releasing the global read lock mysqlsh's own dump
+// consistency relies on, and running the privileged SQL cleanupMySQL issues post-restore.
+type MysqlDaemon interface {
+	GetVersionString(ctx context.Context) (string, error)
+	AcquireGlobalReadLock(ctx context.Context) error
+	ReleaseGlobalReadLock(ctx context.Context) error
+	FetchSuperQuery(ctx context.Context, query string) (*sqltypes.Result, error)
+	ExecuteSuperQuery(ctx context.Context, query string) error
+}
+
+// BackupHandle is the destination a single backup writes its files to, as provided by a
+// BackupStorage implementation.
+type BackupHandle interface {
+	AddFile(ctx context.Context, filename string, filesize int64) (io.WriteCloser, error)
+	EndBackup(ctx context.Context) error
+	AbortBackup(ctx context.Context) error
+}
+
+// BackupStorage opens BackupHandles for a backup engine to write to.
+type BackupStorage interface {
+	StartBackup(ctx context.Context, dir, name string) (BackupHandle, error)
+}
+
+// BackupParams bundles everything ExecuteBackup needs for a single backup run.
+type BackupParams struct {
+	TabletAlias string
+	Logger      logutil.Logger
+	Mysqld      MysqlDaemon
+
+	// ReplicaLagSampler, if set, returns the current worst-case replication lag across
+	// --mysql-shell-throttle-control-replicas; it is the caller's responsibility to resolve
+	// those tablet aliases, since doing so needs a topology server this package does not have
+	// access to. Backups proceed unthrottled by replica lag when nil.
+	ReplicaLagSampler func(ctx context.Context) (time.Duration, error)
+
+	// SchemaFilter is merged into --mysql-shell-dump-options before mysqlsh is invoked, so
+	// partial-shard/table-level backups don't require hand-authoring the includeSchemas/
+	// excludeSchemas/includeTables/excludeTables JSON.
+	SchemaFilter MySQLShellSchemaFilter
+}
+
+// RestoreParams bundles everything restorePreCheck/cleanupMySQL need for a single restore run.
+type RestoreParams struct {
+	Mysqld MysqlDaemon
+	Logger logutil.Logger
+
+	// SchemaFilter is merged into --mysql-shell-load-flags before mysqlsh is invoked, mirroring
+	// BackupParams.SchemaFilter for the restore side.
+	SchemaFilter MySQLShellSchemaFilter
+
+	// CleanupPolicy governs which users/databases cleanupMySQL preserves beyond its built-in
+	// reserved set, and whether it only logs the DROPs it would issue. When nil, ExecuteRestore
+	// builds one from --restore-preserve-users before calling cleanupMySQL.
+	CleanupPolicy *CleanupPolicy
+}
+
+// MySQLShellBackupEngine takes and restores backups by shelling out to mysqlsh's
+// util.dumpInstance/util.loadDump.
+type MySQLShellBackupEngine struct {
+	// binaryName overrides the mysqlsh binary invoked; tests point it at a fake script. Empty
+	// means the "mysqlsh" found on PATH.
+	binaryName string
+}
+
+// NewMySQLShellBackupEngine constructs a MySQLShellBackupEngine, resetting its backupstats
+// gauges to zero so a fresh process (including one recovering from a crash mid-backup) never
+// reports a stale in-progress/lock-held value left over from a previous run.
+func NewMySQLShellBackupEngine(binaryName string) *MySQLShellBackupEngine {
+	backupstats.ResetEngine(mysqlShellBackupEngineName)
+	return &MySQLShellBackupEngine{binaryName: binaryName}
+}
+
+func (be *MySQLShellBackupEngine) binary() string {
+	if be.binaryName != "" {
+		return be.binaryName
+	}
+	return "mysqlsh"
+}
+
+// ShouldDrainForBackup reports whether the tablet must be drained of query serving before this
+// engine's backup runs, per --mysql-shell-should-drain. req is accepted (rather than a
+// zero-argument signature) so future per-request drain overrides have a place to plug in.
+func (be *MySQLShellBackupEngine) ShouldDrainForBackup(req *tabletmanagerdatapb.BackupRequest) bool {
+	return mysqlShellBackupShouldDrain
+}
+
+// backupPreCheck validates the engine's flag configuration and that path's parent directory is
+// usable, before a backup is attempted against it.
+func (be *MySQLShellBackupEngine) backupPreCheck(path string) error {
+	if mysqlShellBackupLocation == "" {
+		return fmt.Errorf("%w: --mysql-shell-backup-location must be set", ErrMySQLShellPreCheck)
+	}
+	if !strings.Contains(mysqlShellFlags, "--js") {
+		return fmt.Errorf("%w: --mysql-shell-flags must include --js so mysqlsh runs in scripting mode", ErrMySQLShellPreCheck)
+	}
+	dir := filepath.Dir(path)
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return fmt.Errorf("%w: backup directory %q is not accessible: %v", ErrMySQLShellPreCheck, dir, err)
+	}
+	return nil
+}
+
+// backupPreCheckWithFilter runs backupPreCheck and additionally validates filter, so a
+// conflicting or sidecar-excluding SchemaFilter is rejected before mysqlsh is ever invoked
+// rather than surfacing as an opaque mysqlsh failure partway through the dump.
+func (be *MySQLShellBackupEngine) backupPreCheckWithFilter(path string, filter MySQLShellSchemaFilter) error {
+	if err := be.backupPreCheck(path); err != nil {
+		return err
+	}
+	return filter.Validate()
+}
+
+var mysqlVersionRE = regexp.MustCompile(`\bVer\s+(\d+)\.(\d+)\.(\d+)`)
+
+// supportsDisableRedoLog reports whether versionString (as returned by `mysqld --version`)
+// supports ALTER INSTANCE DISABLE INNODB REDO_LOG, available from MySQL/Percona Server 8.0.21.
+func supportsDisableRedoLog(versionString string) bool {
+	match := mysqlVersionRE.FindStringSubmatch(versionString)
+	if match == nil {
+		return false
+	}
+	major, _ := strconv.Atoi(match[1])
+	minor, _ := strconv.Atoi(match[2])
+	patch, _ := strconv.Atoi(match[3])
+	if major != 8 {
+		return major > 8
+	}
+	if minor != 0 {
+		return minor > 0
+	}
+	return patch >= 21
+}
+
+type mysqlShellLoadOptions struct {
+	UpdateGtidSet string `json:"updateGtidSet"`
+	ProgressFile  string `json:"progressFile"`
+	SkipBinlog    bool   `json:"skipBinlog"`
+	LoadUsers     bool   `json:"loadUsers"`
+}
+
+// restorePreCheck validates --mysql-shell-load-flags (and, when --mysql-shell-speedup-restore
+// is set, that params.Mysqld's version supports disabling the redo log) before a restore is
+// attempted. It reports whether the restore's cleanup step should delete non-reserved users,
+// per the parsed loadUsers option.
+func (be *MySQLShellBackupEngine) restorePreCheck(ctx context.Context, params RestoreParams) (shouldDeleteUsers bool, err error) {
+	if mysqlShellSpeedUpRestore {
+		version, err := params.Mysqld.GetVersionString(ctx)
+		if err != nil {
+			return false, fmt.Errorf("mysqlshellbackupengine: reading mysqld version: %w", err)
+		}
+		if !supportsDisableRedoLog(version) {
+			return false, fmt.Errorf("%w: --mysql-shell-speedup-restore requires MySQL/Percona Server >= 8.0.21 (disable_redo_log support); got %q", ErrMySQLShellPreCheck, version)
+		}
+	}
+
+	if err := params.SchemaFilter.Validate(); err != nil {
+		return false, err
+	}
+
+	var options mysqlShellLoadOptions
+	if mysqlShellLoadFlags != "" {
+		if err := json.Unmarshal([]byte(mysqlShellLoadFlags), &options); err != nil {
+			return false, fmt.Errorf("%w: invalid --mysql-shell-load-flags JSON: %v", ErrMySQLShellPreCheck, err)
+		}
+	}
+	if options.UpdateGtidSet != "replace" {
+		return false, fmt.Errorf(`%w: updateGtidSet must be "replace"`, ErrMySQLShellPreCheck)
+	}
+	if options.ProgressFile != "" {
+		return false, fmt.Errorf("%w: progressFile must be empty, mysqlsh's own restore progress tracking is not supported here", ErrMySQLShellPreCheck)
+	}
+	return options.LoadUsers, nil
+}
+
+// reservedDatabases/reservedUsers are cleanupMySQL's built-in, always-preserved set, regardless
+// of any CleanupPolicy passed through RestoreParams.
+var reservedDatabases = map[string]bool{
+	"mysql":              true,
+	"information_schema": true,
+	"performance_schema": true,
+	"sys":                true,
+}
+
+var reservedUsers = map[string]bool{
+	"mysql.sys":        true,
+	"mysql.infoschema": true,
+	"mysql.session":    true,
+}
+
+// cleanupMySQL drops every non-reserved database left over from mysqlsh's restore, and, when
+// shouldDeleteUsers is set, every non-reserved user account. params.CleanupPolicy, when set,
+// can preserve additional users/databases beyond the built-in reserved set and can turn every
+// DROP into a dry-run log line instead of executing it.
+func cleanupMySQL(ctx context.Context, params RestoreParams, shouldDeleteUsers bool) error {
+	policy := params.CleanupPolicy
+
+	databasesResult, err := params.Mysqld.FetchSuperQuery(ctx, "SHOW DATABASES")
+	if err != nil {
+		return fmt.Errorf("mysqlshellbackupengine: listing databases for cleanup: %w", err)
+	}
+	for _, row := range databasesResult.Rows {
+		db := row[0].ToString()
+		if reservedDatabases[db] || (policy != nil && policy.ShouldPreserveDatabase(db)) {
+			continue
+		}
+		statement := fmt.Sprintf("DROP DATABASE IF EXISTS `%s`", db)
+		if err := runCleanupStatement(ctx, params, policy, statement); err != nil {
+			return fmt.Errorf("mysqlshellbackupengine: dropping database %q: %w", db, err)
+		}
+	}
+
+	if !shouldDeleteUsers {
+		return nil
+	}
+
+	usersResult, err := params.Mysqld.FetchSuperQuery(ctx, "SELECT user, host FROM mysql.user")
+	if err != nil {
+		return fmt.Errorf("mysqlshellbackupengine: listing users for cleanup: %w", err)
+	}
+	for _, row := range usersResult.Rows {
+		user, host := row[0].ToString(), row[1].ToString()
+		if reservedUsers[user] {
+			continue
+		}
+		if policy != nil {
+			preserve, err := policy.ShouldPreserveUser(user, host)
+			if err != nil {
+				return fmt.Errorf("mysqlshellbackupengine: evaluating cleanup policy for %q@%q: %w", user, host, err)
+			}
+			if preserve {
+				continue
+			}
+		}
+		statement := fmt.Sprintf("DROP USER '%s'@'%s'", user, host)
+		if err := runCleanupStatement(ctx, params, policy, statement); err != nil {
+			return fmt.Errorf("mysqlshellbackupengine: dropping user %q@%q: %w", user, host, err)
+		}
+	}
+	return nil
+}
+
+// runCleanupStatement executes statement via params.Mysqld, or, when policy is set, defers to
+// CleanupPolicy.LogOrExecute so a DryRun policy logs rather than runs it.
+func runCleanupStatement(ctx context.Context, params RestoreParams, policy *CleanupPolicy, statement string) error {
+	execute := func() error { return params.Mysqld.ExecuteSuperQuery(ctx, statement) }
+	if policy == nil {
+		return execute()
+	}
+	return policy.LogOrExecute(statement, params.Logger.Infof, execute)
+}
+
+// buildThrottleChecks assembles the ThrottleChecks ExecuteBackup's Throttler should sample,
+// from whichever of the --mysql-shell-throttle-file, --mysql-shell-throttle-query and
+// --mysql-shell-throttle-control-replicas signals are configured; --mysql-shell-throttle-http
+// is not turned into a check. A signal whose prerequisite
+// isn't available (e.g. replica lag with no params.ReplicaLagSampler) is simply omitted, rather
+// than treated as an error: throttling is always best-effort backpressure, never a precondition
+// for taking the backup.
+func buildThrottleChecks(ctx context.Context, params BackupParams) map[ThrottleSignal]ThrottleCheck {
+	checks := map[ThrottleSignal]ThrottleCheck{}
+
+	if mysqlShellThrottleFile != "" {
+		checks[ThrottleSignalFile] = ThrottleFileCheck(mysqlShellThrottleFile)
+	}
+
+	if mysqlShellThrottleQuery != "" && params.Mysqld != nil {
+		checks[ThrottleSignalQuery] = func() (bool, string, error) {
+			result, err := params.Mysqld.FetchSuperQuery(ctx, mysqlShellThrottleQuery)
+			if err != nil {
+				return false, "", fmt.Errorf("mysqlshellbackupengine: running --mysql-shell-throttle-query: %w", err)
+			}
+			if len(result.Rows) == 0 || len(result.Rows[0]) == 0 {
+				return false, "", fmt.Errorf("mysqlshellbackupengine: --mysql-shell-throttle-query returned no rows")
+			}
+			value, err := result.Rows[0][0].ToFloat64()
+			if err != nil {
+				return false, "", fmt.Errorf("mysqlshellbackupengine: parsing --mysql-shell-throttle-query result: %w", err)
+			}
+			if value > mysqlShellThrottleQueryMax {
+				return true, fmt.Sprintf("throttle query value %.2f exceeds max %.2f", value, mysqlShellThrottleQueryMax), nil
+			}
+			return false, "", nil
+		}
+	}
+
+	if mysqlShellThrottleControlReplicas != "" && params.ReplicaLagSampler != nil {
+		checks[ThrottleSignalReplicaLag] = ReplicaLagCheck(mysqlShellThrottleMaxLag, func() (time.Duration, error) {
+			return params.ReplicaLagSampler(ctx)
+		})
+	}
+
+	return checks
+}
+
+// ExecuteBackup runs mysqlsh against params.Mysqld, writing its dump and a MySQLShellBackupManifest
+// into bh. It holds params.Mysqld's global read lock until mysqlsh reports it has acquired its
+// own (or, failing that, until mysqlsh exits), and throttles the running mysqlsh process via
+// buildThrottleChecks for as long as any configured --mysql-shell-throttle-* signal is active.
+func (be *MySQLShellBackupEngine) ExecuteBackup(ctx context.Context, params BackupParams, bh BackupHandle) (complete bool, err error) {
+	backupstats.BackupStarted(mysqlShellBackupEngineName)
+	defer backupstats.BackupEnded(mysqlShellBackupEngineName)
+
+	lockingTimer := backupstats.StartBackupPhase(mysqlShellBackupEngineName, backupstats.PhaseLocking)
+	acquireErr := params.Mysqld.AcquireGlobalReadLock(ctx)
+	lockingTimer.Done()
+	if acquireErr != nil {
+		return false, fmt.Errorf("mysqlshellbackupengine: acquiring global read lock: %w", acquireErr)
+	}
+
+	startedAt := time.Now()
+	var lockMu sync.Mutex
+	lockReleased := false
+	releaseLock := func(early bool) {
+		lockMu.Lock()
+		defer lockMu.Unlock()
+		if lockReleased {
+			return
+		}
+		if err := params.Mysqld.ReleaseGlobalReadLock(ctx); err != nil {
+			params.Logger.Errorf("mysqlshellbackupengine: failed to release global read lock: %v", err)
+			return
+		}
+		lockReleased = true
+		backupstats.RecordGlobalLockHeld(mysqlShellBackupEngineName, time.Since(startedAt))
+		if !early {
+			params.Logger.Warningf("mysqlshellbackupengine: could not release global lock earlier, mysqlsh never reported acquiring its own")
+		}
+		params.Logger.Infof("mysqlshellbackupengine: global read lock released after %s", time.Since(startedAt))
+	}
+	defer releaseLock(false)
+
+	dumpOptionsJSON, err := MergeSchemaFilterJSON(mysqlShellDumpOptions, params.SchemaFilter)
+	if err != nil {
+		return false, err
+	}
+	cmd := exec.CommandContext(ctx, be.binary(), "--dump-options", dumpOptionsJSON)
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return false, fmt.Errorf("mysqlshellbackupengine: piping mysqlsh stderr: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return false, fmt.Errorf("mysqlshellbackupengine: starting mysqlsh: %w", err)
+	}
+
+	var throttler *Throttler
+	if checks := buildThrottleChecks(ctx, params); len(checks) > 0 {
+		throttler = NewThrottler(checks, 0)
+		stopThrottler := throttler.Run(cmd.Process.Pid, func(format string, args ...any) {
+			params.Logger.Infof(format, args...)
+		})
+		defer stopThrottler()
+	}
+
+	scanDone := make(chan struct{})
+	go func() {
+		defer close(scanDone)
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if strings.Contains(line, mysqlShellLockMessage) {
+				releaseLock(true)
+			}
+		}
+	}()
+
+	dumpingTimer := backupstats.StartBackupPhase(mysqlShellBackupEngineName, backupstats.PhaseDumping)
+	runErr := cmd.Wait()
+	<-scanDone
+	dumpingTimer.Done()
+	if runErr != nil {
+		return false, fmt.Errorf("mysqlshellbackupengine: mysqlshell failed: %w", runErr)
+	}
+
+	var throttleStats ThrottleStats
+	if throttler != nil {
+		throttleStats = throttler.Stats()
+	}
+	uploadingTimer := backupstats.StartBackupPhase(mysqlShellBackupEngineName, backupstats.PhaseUploading)
+	manifestErr := be.writeManifest(ctx, bh, throttleStats)
+	uploadingTimer.Done()
+	if manifestErr != nil {
+		return false, manifestErr
+	}
+	return true, nil
+}
+
+func (be *MySQLShellBackupEngine) writeManifest(ctx context.Context, bh BackupHandle, throttleStats ThrottleStats) error {
+	manifest := MySQLShellBackupManifest{
+		BackupMethod:              mysqlShellBackupEngineName,
+		ThrottledDuration:         throttleStats.ThrottledDuration,
+		ThrottleSignalPauseCounts: throttleStats.SignalPauseCounts,
+	}
+	data, err := json.MarshalIndent(&manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("mysqlshellbackupengine: marshaling manifest: %w", err)
+	}
+	writer, err := bh.AddFile(ctx, "MANIFEST", int64(len(data)))
+	if err != nil {
+		return fmt.Errorf("mysqlshellbackupengine: adding manifest file: %w", err)
+	}
+	backupstats.AddBytesWritten(mysqlShellBackupEngineName, int64(len(data)))
+	if _, err := writer.Write(data); err != nil {
+		writer.Close()
+		return fmt.Errorf("mysqlshellbackupengine: writing manifest: %w", err)
+	}
+	return writer.Close()
+}
+
+// ExecuteRestore runs restorePreCheck, loads the dump via mysqlsh under params.Mysqld's global
+// read lock, and finally runs cleanupMySQL, timing each stage into the backupstats restore
+// phase gauges.
+func (be *MySQLShellBackupEngine) ExecuteRestore(ctx context.Context, params RestoreParams) (err error) {
+	backupstats.RestoreStarted(mysqlShellBackupEngineName)
+	defer backupstats.RestoreEnded(mysqlShellBackupEngineName)
+
+	precheckTimer := backupstats.StartRestorePhase(mysqlShellBackupEngineName, backupstats.PhasePrecheck)
+	shouldDeleteUsers, err := be.restorePreCheck(ctx, params)
+	precheckTimer.Done()
+	if err != nil {
+		return err
+	}
+
+	lockingTimer := backupstats.StartRestorePhase(mysqlShellBackupEngineName, backupstats.PhaseLocking)
+	lockedAt := time.Now()
+	acquireErr := params.Mysqld.AcquireGlobalReadLock(ctx)
+	lockingTimer.Done()
+	if acquireErr != nil {
+		return fmt.Errorf("mysqlshellbackupengine: acquiring global read lock for restore: %w", acquireErr)
+	}
+
+	loadOptionsJSON, err := MergeSchemaFilterJSON(mysqlShellLoadFlags, params.SchemaFilter)
+	if err != nil {
+		return err
+	}
+	dumpingTimer := backupstats.StartRestorePhase(mysqlShellBackupEngineName, backupstats.PhaseDumping)
+	runErr := exec.CommandContext(ctx, be.binary(), "--load-options", loadOptionsJSON).Run()
+	dumpingTimer.Done()
+
+	unlockTimer := backupstats.StartRestorePhase(mysqlShellBackupEngineName, backupstats.PhaseUnlock)
+	releaseErr := params.Mysqld.ReleaseGlobalReadLock(ctx)
+	unlockTimer.Done()
+	if releaseErr != nil {
+		return fmt.Errorf("mysqlshellbackupengine: releasing global read lock after restore: %w", releaseErr)
+	}
+	backupstats.RecordRestoreGlobalLockHeld(mysqlShellBackupEngineName, time.Since(lockedAt))
+
+	if runErr != nil {
+		return fmt.Errorf("mysqlshellbackupengine: mysqlsh load failed: %w", runErr)
+	}
+
+	if params.CleanupPolicy == nil {
+		preservePatterns, parseErr := ParsePreserveUsersFlag(mysqlShellRestorePreserveUsers)
+		if parseErr != nil {
+			return parseErr
+		}
+		if len(preservePatterns) > 0 {
+			params.CleanupPolicy = &CleanupPolicy{PreserveUserPatterns: preservePatterns}
+		}
+	}
+
+	cleanupTimer := backupstats.StartRestorePhase(mysqlShellBackupEngineName, backupstats.PhaseCleanup)
+	err = cleanupMySQL(ctx, params, shouldDeleteUsers)
+	cleanupTimer.Done()
+	return err
+}
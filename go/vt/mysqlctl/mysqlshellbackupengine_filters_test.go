@@ -0,0 +1,124 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mysqlctl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vitess.io/vitess/go/mysql/fakesqldb"
+	"vitess.io/vitess/go/vt/logutil"
+)
+
+// TestMySQLShellBackupEngine_BackupPreCheckWithFilter proves backupPreCheckWithFilter rejects a
+// SchemaFilter before mysqlsh is ever invoked, rather than letting it surface as an opaque
+// mysqlsh failure partway through the dump.
+func TestMySQLShellBackupEngine_BackupPreCheckWithFilter(t *testing.T) {
+	originalLocation := mysqlShellBackupLocation
+	originalFlags := mysqlShellFlags
+	defer func() {
+		mysqlShellBackupLocation = originalLocation
+		mysqlShellFlags = originalFlags
+	}()
+	mysqlShellBackupLocation = "/dev/null"
+	mysqlShellFlags = `{"--js": true}`
+
+	engine := MySQLShellBackupEngine{}
+	tests := []struct {
+		name   string
+		filter MySQLShellSchemaFilter
+		err    error
+	}{
+		{
+			"no filter",
+			MySQLShellSchemaFilter{},
+			nil,
+		},
+		{
+			"conflicting include/exclude schema",
+			MySQLShellSchemaFilter{IncludeSchemas: []string{"commerce"}, ExcludeSchemas: []string{"commerce"}},
+			ErrMySQLShellPreCheck,
+		},
+		{
+			"excludes the Vitess sidecar schema",
+			MySQLShellSchemaFilter{ExcludeSchemas: []string{"_vt"}},
+			ErrMySQLShellPreCheck,
+		},
+	}
+
+	backupPath := filepath.Join(t.TempDir(), "backup")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := engine.backupPreCheckWithFilter(backupPath, tt.filter)
+			if tt.err == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, tt.err))
+		})
+	}
+}
+
+// TestMySQLShellBackupEngine_RestorePreCheckRejectsConflictingFilter proves restorePreCheck
+// validates params.SchemaFilter alongside --mysql-shell-load-flags.
+func TestMySQLShellBackupEngine_RestorePreCheckRejectsConflictingFilter(t *testing.T) {
+	originalLoadFlags := mysqlShellLoadFlags
+	defer func() { mysqlShellLoadFlags = originalLoadFlags }()
+	mysqlShellLoadFlags = `{"updateGtidSet": "replace"}`
+
+	fakedb := fakesqldb.New(t)
+	defer fakedb.Close()
+	mysql := NewFakeMysqlDaemon(fakedb)
+	defer mysql.Close()
+
+	engine := MySQLShellBackupEngine{}
+	params := RestoreParams{
+		Mysqld: mysql,
+		Logger: logutil.NewMemoryLogger(),
+		SchemaFilter: MySQLShellSchemaFilter{
+			IncludeTables: []string{"corder"},
+			ExcludeTables: []string{"corder"},
+		},
+	}
+
+	_, err := engine.restorePreCheck(context.Background(), params)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrMySQLShellPreCheck))
+}
+
+// TestMergeSchemaFilterJSON_Merging proves MergeSchemaFilterJSON folds a SchemaFilter's fields
+// into an existing raw JSON options blob without discarding keys the user already set.
+func TestMergeSchemaFilterJSON_Merging(t *testing.T) {
+	merged, err := MergeSchemaFilterJSON(`{"threads": 4}`, MySQLShellSchemaFilter{
+		IncludeSchemas: []string{"commerce"},
+		ExcludeTables:  []string{"commerce.audit_log"},
+	})
+	require.NoError(t, err)
+
+	var options map[string]any
+	require.NoError(t, json.Unmarshal([]byte(merged), &options))
+	assert.Equal(t, float64(4), options["threads"])
+	assert.Equal(t, []any{"commerce"}, options["includeSchemas"])
+	assert.Equal(t, []any{"commerce.audit_log"}, options["excludeTables"])
+}
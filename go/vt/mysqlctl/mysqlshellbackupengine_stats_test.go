@@ -0,0 +1,73 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mysqlctl
+
+import (
+	"context"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vitess.io/vitess/go/ioutil"
+	"vitess.io/vitess/go/mysql/fakesqldb"
+	"vitess.io/vitess/go/vt/logutil"
+	"vitess.io/vitess/go/vt/mysqlctl/backupstats"
+)
+
+// TestMySQLShellBackupEngine_ExecuteBackup_Stats proves ExecuteBackup publishes
+// backupstats.BackupInProgress/BackupBytesWritten, and that a second run doesn't inherit the
+// previous run's in-progress state.
+func TestMySQLShellBackupEngine_ExecuteBackup_Stats(t *testing.T) {
+	originalLocation := mysqlShellBackupLocation
+	mysqlShellBackupLocation = "logical"
+	defer func() { mysqlShellBackupLocation = originalLocation }()
+
+	logger := logutil.NewMemoryLogger()
+	fakedb := fakesqldb.New(t)
+	defer fakedb.Close()
+	mysql := NewFakeMysqlDaemon(fakedb)
+	defer mysql.Close()
+	params := BackupParams{TabletAlias: "test", Logger: logger, Mysqld: mysql}
+
+	runOnce := func(script string) (complete bool, err error) {
+		be := NewMySQLShellBackupEngine(path.Join(t.TempDir(), "mysqlsh.sh"))
+		generateTestFile(t, be.binaryName, script)
+		manifestBuffer := ioutil.NewBytesBufferWriter()
+		bh := &FakeBackupHandle{
+			Dir:           t.TempDir(),
+			AddFileReturn: FakeBackupHandleAddFileReturn{WriteCloser: manifestBuffer},
+		}
+		assert.Zero(t, backupstats.BackupInProgressValue(mysqlShellBackupEngineName),
+			"BackupInProgress must be reset to zero on construction")
+		return be.ExecuteBackup(context.Background(), params, bh)
+	}
+
+	complete, err := runOnce("#!/bin/bash\nexit 0")
+	require.NoError(t, err)
+	require.True(t, complete)
+	assert.Zero(t, backupstats.BackupInProgressValue(mysqlShellBackupEngineName),
+		"BackupInProgress must be cleared once ExecuteBackup returns")
+	assert.Greater(t, backupstats.BytesWrittenValue(mysqlShellBackupEngineName), int64(0))
+
+	// A failed run still must not leave BackupInProgress set, and the next construction still
+	// resets every gauge rather than inheriting the failure.
+	_, err = runOnce("#!/bin/bash\nexit 1")
+	require.Error(t, err)
+	assert.Zero(t, backupstats.BackupInProgressValue(mysqlShellBackupEngineName))
+}
@@ -36,6 +36,15 @@ type (
 		stmt        sqlparser.Statement
 		tableNames  []string
 		dmlOperator Operator
+		// cteMemo tracks operator subtrees shared by more than one parent so
+		// they can be hoisted once into a WITH-clause CTE instead of being
+		// duplicated in the emitted SQL. It is shared with every sub-builder
+		// spawned while building this statement.
+		cteMemo *subplanMemo
+		// exprKind records which clause of the emitted statement is
+		// currently being populated; withExprKind sets it for the duration
+		// of each addition after validating the expression for that clause.
+		exprKind ExprKind
 	}
 )
 
@@ -50,11 +59,17 @@ func (qb *queryBuilder) asOrderAndLimit() sqlparser.OrderAndLimit {
 func ToSQL(ctx *plancontext.PlanningContext, op Operator) (_ sqlparser.Statement, _ Operator, err error) {
 	defer PanicHandler(&err)
 
-	q := &queryBuilder{ctx: ctx}
+	op = applyRewriteRules(PhasePostPhysical, op)
+
+	memo := newSubplanMemo()
+	memo.countRefs(op)
+
+	q := &queryBuilder{ctx: ctx, cteMemo: memo}
 	buildQuery(op, q)
 	if ctx.SemTable != nil {
 		q.sortTables()
 	}
+	q.hoistSharedCTEs()
 	return q.stmt, q.dmlOperator, nil
 }
 
@@ -128,10 +143,12 @@ func (qb *queryBuilder) addPredicate(expr sqlparser.Expr) {
 	}
 
 	var addPred func(sqlparser.Expr)
+	kind := ExprKindWhere
 
 	switch stmt := qb.stmt.(type) {
 	case *sqlparser.Select:
 		if qb.ctx.ContainsAggr(expr) {
+			kind = ExprKindHaving
 			addPred = stmt.AddHaving
 		} else {
 			addPred = stmt.AddWhere
@@ -150,14 +167,18 @@ func (qb *queryBuilder) addPredicate(expr sqlparser.Expr) {
 		panic(fmt.Sprintf("cant add WHERE to %T, %s", qb.stmt, sqlparser.String(expr)))
 	}
 
-	for _, exp := range sqlparser.SplitAndExpression(nil, expr) {
-		addPred(exp)
-	}
+	qb.withExprKind(kind, expr, func() {
+		for _, exp := range sqlparser.SplitAndExpression(nil, expr) {
+			addPred(exp)
+		}
+	})
 }
 
 func (qb *queryBuilder) addGroupBy(original sqlparser.Expr) {
 	sel := qb.stmt.(*sqlparser.Select)
-	sel.AddGroupBy(original)
+	qb.withExprKind(ExprKindGroupBy, original, func() {
+		sel.AddGroupBy(original)
+	})
 }
 
 func (qb *queryBuilder) setWithRollup() {
@@ -166,23 +187,32 @@ func (qb *queryBuilder) setWithRollup() {
 }
 
 func (qb *queryBuilder) addProjection(projection sqlparser.SelectExpr) {
-	switch stmt := qb.stmt.(type) {
-	case *sqlparser.Select:
-		stmt.AddSelectExpr(projection)
-		return
-	case *sqlparser.Union:
-		if ae, ok := projection.(*sqlparser.AliasedExpr); ok {
-			if col, ok := ae.Expr.(*sqlparser.ColName); ok {
-				checkUnionColumnByName(col, stmt)
-				return
+	addProjection := func() {
+		switch stmt := qb.stmt.(type) {
+		case *sqlparser.Select:
+			stmt.AddSelectExpr(projection)
+			return
+		case *sqlparser.Union:
+			if ae, ok := projection.(*sqlparser.AliasedExpr); ok {
+				if col, ok := ae.Expr.(*sqlparser.ColName); ok {
+					checkUnionColumnByName(col, stmt)
+					return
+				}
 			}
+
+			qb.pushUnionInsideDerived()
+			qb.addProjection(projection)
+			return
 		}
+		panic(vterrors.VT13001(fmt.Sprintf("unknown select statement type: %T", qb.stmt)))
+	}
 
-		qb.pushUnionInsideDerived()
-		qb.addProjection(projection)
+	ae, ok := projection.(*sqlparser.AliasedExpr)
+	if !ok {
+		addProjection()
 		return
 	}
-	panic(vterrors.VT13001(fmt.Sprintf("unknown select statement type: %T", qb.stmt)))
+	qb.withExprKind(ExprKindSelectTarget, ae.Expr, addProjection)
 }
 
 func (qb *queryBuilder) pushUnionInsideDerived() {
@@ -296,18 +326,26 @@ func (qb *queryBuilder) joinWith(other *queryBuilder, onCondition sqlparser.Expr
 
 	qb.mergeWhereClauses(stmt, otherStmt)
 
-	var newFromClause []sqlparser.TableExpr
-	switch joinType {
-	case sqlparser.NormalJoinType:
-		newFromClause = append(stmt.GetFrom(), otherStmt.GetFrom()...)
-		for _, pred := range sqlparser.SplitAndExpression(nil, onCondition) {
-			qb.addPredicate(pred)
+	applyJoin := func() {
+		var newFromClause []sqlparser.TableExpr
+		switch joinType {
+		case sqlparser.NormalJoinType:
+			newFromClause = append(stmt.GetFrom(), otherStmt.GetFrom()...)
+			for _, pred := range sqlparser.SplitAndExpression(nil, onCondition) {
+				qb.addPredicate(pred)
+			}
+		default:
+			newFromClause = []sqlparser.TableExpr{buildJoin(stmt, otherStmt, onCondition, joinType)}
 		}
-	default:
-		newFromClause = []sqlparser.TableExpr{buildJoin(stmt, otherStmt, onCondition, joinType)}
+
+		stmt.SetFrom(newFromClause)
 	}
 
-	stmt.SetFrom(newFromClause)
+	if onCondition == nil {
+		applyJoin()
+		return
+	}
+	qb.withExprKind(ExprKindJoinOn, onCondition, applyJoin)
 }
 
 func (qb *queryBuilder) mergeWhereClauses(stmt, otherStmt FromStatement) {
@@ -458,6 +496,8 @@ func buildQuery(op Operator, qb *queryBuilder) {
 		buildOrdering(op, qb)
 	case *Aggregator:
 		buildAggregation(op, qb)
+	case *Window:
+		buildWindow(op, qb)
 	case *Union:
 		buildUnion(op, qb)
 	case *Distinct:
@@ -493,6 +533,9 @@ func buildDelete(op *Delete, qb *queryBuilder) {
 
 func buildUpdate(op *Update, qb *queryBuilder) {
 	updExprs := getUpdateExprs(op)
+	for _, updExpr := range updExprs {
+		validateExprKind(ExprKindUpdateSet, updExpr.Expr)
+	}
 	upd := &sqlparser.Update{
 		Ignore: op.Ignore,
 		Exprs:  updExprs,
@@ -557,12 +600,19 @@ func buildOrdering(op *Ordering, qb *queryBuilder) {
 	buildQuery(op.Source, qb)
 
 	for _, order := range op.Order {
-		qb.asOrderAndLimit().AddOrder(order.Inner)
+		ord := order
+		qb.withExprKind(ExprKindOrderBy, ord.Inner.Expr, func() {
+			qb.asOrderAndLimit().AddOrder(ord.Inner)
+		})
 	}
 }
 
 func buildLimit(op *Limit, qb *queryBuilder) {
 	buildQuery(op.Source, qb)
+	if op.AST != nil {
+		validateExprKind(ExprKindLimit, op.AST.Rowcount)
+		validateExprKind(ExprKindLimit, op.AST.Offset)
+	}
 	qb.asOrderAndLimit().SetLimit(op.AST)
 }
 
@@ -623,10 +673,10 @@ func buildApplyJoin(op *ApplyJoin, qb *queryBuilder) {
 	})
 	pred := sqlparser.AndExpressions(preds...)
 
-	buildQuery(op.LHS, qb)
+	qb.buildSide(op.LHS)
 
-	qbR := &queryBuilder{ctx: qb.ctx}
-	buildQuery(op.RHS, qbR)
+	qbR := &queryBuilder{ctx: qb.ctx, cteMemo: qb.cteMemo}
+	qbR.buildSide(op.RHS)
 
 	switch {
 	// if we have a recursive cte, we might be missing a statement from one of the sides
@@ -649,7 +699,7 @@ func buildUnion(op *Union, qb *queryBuilder) {
 		}
 
 		// now we can go over the remaining inputs and UNION them together
-		qbOther := &queryBuilder{ctx: qb.ctx}
+		qbOther := &queryBuilder{ctx: qb.ctx, cteMemo: qb.cteMemo}
 		buildQuery(src, qbOther)
 		qb.unionWith(qbOther, op.distinct)
 	}
@@ -723,7 +773,7 @@ func buildHorizon(op *Horizon, qb *queryBuilder) {
 
 func buildRecursiveCTE(op *RecurseCTE, qb *queryBuilder) {
 	buildQuery(op.Seed(), qb)
-	qbR := &queryBuilder{ctx: qb.ctx}
+	qbR := &queryBuilder{ctx: qb.ctx, cteMemo: qb.cteMemo}
 	buildQuery(op.Term(), qbR)
 	infoFor, err := qb.ctx.SemTable.TableInfoFor(op.OuterID)
 	if err != nil {
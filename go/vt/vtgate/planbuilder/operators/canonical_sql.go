@@ -0,0 +1,300 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operators
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strconv"
+
+	"vitess.io/vitess/go/vt/sqlparser"
+	"vitess.io/vitess/go/vt/vtgate/planbuilder/plancontext"
+)
+
+// CanonicalSQL is the result of CanonicalToSQL: a deterministic, textually
+// comparable SQL statement plus a content fingerprint, suitable for keying
+// the plan cache or comparing plans for the same query across shards whose
+// operator trees differ only in table order or alias choice.
+type CanonicalSQL struct {
+	Statement   sqlparser.Statement
+	DMLOperator Operator
+	Fingerprint string
+}
+
+// CanonicalToSQL builds op into SQL the same way ToSQL does, and then
+// canonicalizes the result: tables and AND-chains are sorted into a stable
+// order, redundant derived tables are collapsed, and every alias is
+// renamed to a position-based t0..tN/c0..cN name. Two operator trees that
+// are semantically equivalent but differ only in table order or alias
+// choice produce byte-identical output.
+func CanonicalToSQL(ctx *plancontext.PlanningContext, op Operator) (_ *CanonicalSQL, err error) {
+	defer PanicHandler(&err)
+
+	stmt, dmlOp, err := ToSQL(ctx, op)
+	if err != nil {
+		return nil, err
+	}
+
+	canonicalizeStatement(stmt)
+
+	sql := sqlparser.String(stmt)
+	sum := sha256.Sum256([]byte(sql))
+
+	return &CanonicalSQL{
+		Statement:   stmt,
+		DMLOperator: dmlOp,
+		Fingerprint: hex.EncodeToString(sum[:]),
+	}, nil
+}
+
+// canonicalizeStatement applies the canonicalization passes to every
+// SELECT reachable from stmt (including inside derived tables and CTEs),
+// then renames every table/column alias to a position-based name.
+func canonicalizeStatement(stmt sqlparser.Statement) {
+	_ = sqlparser.Walk(func(node sqlparser.SQLNode) (bool, error) {
+		sel, isSel := node.(*sqlparser.Select)
+		if !isSel {
+			return true, nil
+		}
+		sortPredicates(sel)
+		sortGroupBy(sel)
+		collapseRedundantDerivedTables(sel)
+		return true, nil
+	}, stmt)
+
+	renameAliasesPositionally(stmt)
+}
+
+// sortPredicates sorts the top-level AND-chain of WHERE/HAVING into a
+// stable order keyed by each conjunct's own textual hash, so that
+// `a = 1 AND b = 2` and `b = 2 AND a = 1` canonicalize identically.
+func sortPredicates(sel *sqlparser.Select) {
+	sortExprByHash := func(expr sqlparser.Expr) sqlparser.Expr {
+		if expr == nil {
+			return nil
+		}
+		parts := sqlparser.SplitAndExpression(nil, expr)
+		sort.Slice(parts, func(i, j int) bool {
+			return exprHash(parts[i]) < exprHash(parts[j])
+		})
+		return sqlparser.AndExpressions(parts...)
+	}
+
+	if sel.Where != nil {
+		sel.Where.Expr = sortExprByHash(sel.Where.Expr)
+	}
+	if sel.Having != nil {
+		sel.Having.Expr = sortExprByHash(sel.Having.Expr)
+	}
+}
+
+// sortGroupBy sorts GROUP BY keys by their textual hash, since grouping is
+// order-independent but two equivalent plans may produce the keys in a
+// different order.
+func sortGroupBy(sel *sqlparser.Select) {
+	keys := sel.GetGroupBy()
+	if len(keys) < 2 {
+		return
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return exprHash(keys[i]) < exprHash(keys[j])
+	})
+}
+
+// collapseRedundantDerivedTables removes a derived table wrapper that adds
+// no projection, filter, limit, order, or group of its own and reads from a
+// single table expression - it exists only
+// because buildDerivedSelect/pushUnionInsideDerived needed somewhere to
+// hang a FROM clause, and collapsing it brings the inner SELECT's FROM up
+// a level so two plans that differ only in whether that wrapper was
+// introduced canonicalize identically.
+func collapseRedundantDerivedTables(sel *sqlparser.Select) {
+	for i, tbl := range sel.From {
+		ate, ok := tbl.(*sqlparser.AliasedTableExpr)
+		if !ok {
+			continue
+		}
+		dt, ok := ate.Expr.(*sqlparser.DerivedTable)
+		if !ok {
+			continue
+		}
+		inner, ok := dt.Select.(*sqlparser.Select)
+		if !ok {
+			continue
+		}
+		if len(inner.GroupBy.Columns) > 0 || len(inner.OrderBy) > 0 || !inner.Limit.IsEmpty() || inner.Distinct {
+			continue
+		}
+		if inner.Where != nil || len(inner.From) != 1 {
+			continue
+		}
+		if !isTrivialProjection(inner.SelectExprs) {
+			continue
+		}
+		sel.From[i] = inner.From[0]
+	}
+}
+
+// isTrivialProjection reports whether exprs is just `*`, i.e. the derived
+// table adds no column renaming/computation of its own.
+func isTrivialProjection(exprs sqlparser.SelectExprs) bool {
+	if len(exprs.Exprs) != 1 {
+		return false
+	}
+	_, ok := exprs.Exprs[0].(*sqlparser.StarExpr)
+	return ok
+}
+
+// renameAliasesPositionally walks stmt's SELECTs outside-in, renaming every
+// table alias to t0..tN and every column alias to c0..cN. Each SELECT is
+// handled as its own scope: besides the declarations themselves, every
+// reference to a renamed alias within that same SELECT's own WHERE/ON/
+// GROUP BY/HAVING/ORDER BY/SELECT targets is rewritten in lockstep, so a
+// renamed declaration and its uses never diverge into a dangling reference.
+// Nested SELECTs (derived tables, subqueries) have their own alias scope
+// and get their own declarations-plus-references pass when Walk reaches
+// them in turn.
+func renameAliasesPositionally(stmt sqlparser.Statement) {
+	tableIdx, colIdx := 0, 0
+	_ = sqlparser.Walk(func(node sqlparser.SQLNode) (bool, error) {
+		if sel, ok := node.(*sqlparser.Select); ok {
+			renameSelectAliasesAndRefs(sel, &tableIdx, &colIdx)
+		}
+		return true, nil
+	}, stmt)
+}
+
+// renameSelectAliasesAndRefs renames sel's own table and column aliases to
+// position-based names and rewrites every reference to the old names within
+// sel's own clauses to match.
+func renameSelectAliasesAndRefs(sel *sqlparser.Select, tableIdx, colIdx *int) {
+	tableNames := map[string]sqlparser.IdentifierCS{}
+	for _, tbl := range sel.From {
+		renameTableExprAliases(tbl, tableNames, tableIdx)
+	}
+
+	colNames := map[string]sqlparser.IdentifierCI{}
+	for _, expr := range sel.SelectExprs.Exprs {
+		ae, ok := expr.(*sqlparser.AliasedExpr)
+		if !ok || ae.As.IsEmpty() {
+			continue
+		}
+		old := ae.As
+		ae.As = sqlparser.NewIdentifierCI(positionalName("c", *colIdx))
+		*colIdx++
+		colNames[old.String()] = ae.As
+	}
+
+	for _, tbl := range sel.From {
+		rewriteJoinOnRefs(tbl, tableNames, colNames)
+	}
+	if sel.Where != nil {
+		rewriteColNameRefs(sel.Where.Expr, tableNames, colNames)
+	}
+	if sel.Having != nil {
+		rewriteColNameRefs(sel.Having.Expr, tableNames, colNames)
+	}
+	for _, gb := range sel.GroupBy {
+		rewriteColNameRefs(gb, tableNames, colNames)
+	}
+	for _, ob := range sel.OrderBy {
+		rewriteColNameRefs(ob.Expr, tableNames, colNames)
+	}
+	for _, expr := range sel.SelectExprs.Exprs {
+		if ae, ok := expr.(*sqlparser.AliasedExpr); ok {
+			rewriteColNameRefs(ae.Expr, tableNames, colNames)
+		}
+	}
+}
+
+// renameTableExprAliases renames the table alias(es) declared directly in
+// expr (descending through joins and parenthesized table lists, but not
+// into derived tables' own inner SELECTs), recording old->new in tableNames.
+func renameTableExprAliases(expr sqlparser.TableExpr, tableNames map[string]sqlparser.IdentifierCS, tableIdx *int) {
+	switch e := expr.(type) {
+	case *sqlparser.AliasedTableExpr:
+		if !e.As.IsEmpty() {
+			old := e.As
+			e.As = sqlparser.NewIdentifierCS(positionalName("t", *tableIdx))
+			*tableIdx++
+			tableNames[old.String()] = e.As
+		}
+	case *sqlparser.JoinTableExpr:
+		renameTableExprAliases(e.LeftExpr, tableNames, tableIdx)
+		renameTableExprAliases(e.RightExpr, tableNames, tableIdx)
+	case *sqlparser.ParenTableExpr:
+		for _, t := range e.Exprs {
+			renameTableExprAliases(t, tableNames, tableIdx)
+		}
+	}
+}
+
+// rewriteJoinOnRefs rewrites the table qualifiers used in a join's own ON
+// condition(s) to match the renames recorded in tableNames.
+func rewriteJoinOnRefs(expr sqlparser.TableExpr, tableNames map[string]sqlparser.IdentifierCS, colNames map[string]sqlparser.IdentifierCI) {
+	switch e := expr.(type) {
+	case *sqlparser.JoinTableExpr:
+		rewriteJoinOnRefs(e.LeftExpr, tableNames, colNames)
+		rewriteJoinOnRefs(e.RightExpr, tableNames, colNames)
+		if e.Condition.On != nil {
+			rewriteColNameRefs(e.Condition.On, tableNames, colNames)
+		}
+	case *sqlparser.ParenTableExpr:
+		for _, t := range e.Exprs {
+			rewriteJoinOnRefs(t, tableNames, colNames)
+		}
+	}
+}
+
+// rewriteColNameRefs rewrites every ColName within expr whose qualifier
+// names a renamed table, or whose unqualified name refers to a renamed
+// SELECT-list alias (as an ORDER BY/GROUP BY/HAVING expression may), to use
+// the new positional name instead. It does not descend into a nested
+// Subquery: that Subquery's own SELECT has its own alias scope and is
+// rewritten on its own turn by renameAliasesPositionally's outer Walk.
+func rewriteColNameRefs(expr sqlparser.Expr, tableNames map[string]sqlparser.IdentifierCS, colNames map[string]sqlparser.IdentifierCI) {
+	_ = sqlparser.Walk(func(node sqlparser.SQLNode) (bool, error) {
+		switch node := node.(type) {
+		case *sqlparser.Subquery:
+			return false, nil
+		case *sqlparser.ColName:
+			if !node.Qualifier.Name.IsEmpty() {
+				if newName, ok := tableNames[node.Qualifier.Name.String()]; ok {
+					node.Qualifier.Name = newName
+				}
+				return true, nil
+			}
+			if newName, ok := colNames[node.Name.String()]; ok {
+				node.Name = newName
+			}
+		}
+		return true, nil
+	}, expr)
+}
+
+func positionalName(prefix string, idx int) string {
+	return prefix + strconv.Itoa(idx)
+}
+
+// exprHash returns a stable sort key for expr based on its canonical SQL
+// text, used to order structurally unordered expression lists (AND-chains,
+// GROUP BY keys) deterministically.
+func exprHash(expr sqlparser.Expr) string {
+	sum := sha256.Sum256([]byte(sqlparser.String(expr)))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operators
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"vitess.io/vitess/go/vt/sqlparser"
+)
+
+// TestCollapseRedundantDerivedTablesPreservesWhere asserts a derived table's own WHERE clause
+// is never dropped: collapsing `(SELECT * FROM t WHERE id > 5) AS dt` down to `FROM t` would
+// silently produce a broader result set than the original query.
+func TestCollapseRedundantDerivedTablesPreservesWhere(t *testing.T) {
+	parser := sqlparser.NewTestParser()
+	stmt, err := parser.Parse("select * from (select * from t where id > 5) as dt")
+	require.NoError(t, err)
+	sel := stmt.(*sqlparser.Select)
+
+	collapseRedundantDerivedTables(sel)
+
+	require.Len(t, sel.From, 1)
+	ate, ok := sel.From[0].(*sqlparser.AliasedTableExpr)
+	require.True(t, ok, "derived table with a WHERE clause must not be collapsed")
+	_, ok = ate.Expr.(*sqlparser.DerivedTable)
+	require.True(t, ok, "derived table with a WHERE clause must not be collapsed")
+}
+
+// TestCollapseRedundantDerivedTablesPreservesMultiTableFrom asserts a derived table whose own
+// FROM joins more than one table is never collapsed down to just its first table: collapsing
+// `(SELECT * FROM a, b) AS dt` down to `FROM a` would silently drop table b entirely.
+func TestCollapseRedundantDerivedTablesPreservesMultiTableFrom(t *testing.T) {
+	parser := sqlparser.NewTestParser()
+	stmt, err := parser.Parse("select * from (select * from a, b) as dt")
+	require.NoError(t, err)
+	sel := stmt.(*sqlparser.Select)
+
+	collapseRedundantDerivedTables(sel)
+
+	require.Len(t, sel.From, 1)
+	ate, ok := sel.From[0].(*sqlparser.AliasedTableExpr)
+	require.True(t, ok, "derived table with more than one FROM table must not be collapsed")
+	_, ok = ate.Expr.(*sqlparser.DerivedTable)
+	require.True(t, ok, "derived table with more than one FROM table must not be collapsed")
+}
+
+// TestRenameAliasesPositionallyRewritesReferences asserts that renaming a
+// table alias also rewrites every place that alias is referenced, so the
+// renamed statement still parses and no longer mentions the original alias
+// anywhere - neither in its declaration nor in any of its uses.
+func TestRenameAliasesPositionallyRewritesReferences(t *testing.T) {
+	parser := sqlparser.NewTestParser()
+	stmt, err := parser.Parse("select a.x, b.y as total from t as a, t as b where a.id = b.id order by total")
+	require.NoError(t, err)
+	sel := stmt.(*sqlparser.Select)
+
+	renameAliasesPositionally(sel)
+
+	out := sqlparser.String(sel)
+
+	require.NotContains(t, out, "a.", "old table alias must not survive as a dangling reference")
+	require.NotContains(t, out, "b.", "old table alias must not survive as a dangling reference")
+	require.NotContains(t, out, "total", "old column alias must not survive as a dangling reference")
+
+	// the renamed statement must still parse: a dangling reference to an
+	// alias that no longer exists would still be syntactically valid SQL
+	// but semantically broken, so re-parsing alone isn't sufficient - also
+	// check the WHERE predicate now refers to the renamed table aliases.
+	reparsed, err := parser.Parse(out)
+	require.NoError(t, err)
+	require.NotNil(t, reparsed)
+
+	require.True(t, strings.Contains(out, "t0.id = t1.id") || strings.Contains(out, "t1.id = t0.id"),
+		"WHERE predicate referencing renamed aliases must itself be rewritten, got: %s", out)
+}
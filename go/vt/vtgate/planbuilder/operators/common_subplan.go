@@ -0,0 +1,283 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operators
+
+import (
+	"fmt"
+	"strings"
+
+	"vitess.io/vitess/go/vt/sqlparser"
+)
+
+// buildSide builds the SQL for a join side into qb, hoisting it into a
+// shared CTE instead of emitting it inline when the subtree is reachable
+// from more than one parent in the operator DAG.
+func (qb *queryBuilder) buildSide(op Operator) {
+	if qb.cteMemo != nil && qb.cteMemo.shared(op) {
+		qb.useSharedSubplan(op)
+		return
+	}
+	buildQuery(op, qb)
+}
+
+// useSharedSubplan materializes op as a CTE the first time it is seen, and
+// simply references the previously materialized CTE on later visits.
+func (qb *queryBuilder) useSharedSubplan(op Operator) {
+	name := qb.cteMemo.cteNameFor(op)
+
+	if _, ok := qb.cteMemo.builtCTE(op); !ok {
+		sub := &queryBuilder{ctx: qb.ctx, cteMemo: qb.cteMemo}
+		buildQuery(op, sub)
+		cte := &sqlparser.CommonTableExpr{
+			ID:       sqlparser.NewIdentifierCS(name),
+			Subquery: sub.asSelectStatement(),
+		}
+		qb.cteMemo.markBuilt(op, cte)
+	}
+
+	qb.addTable("", name, name, "", nil)
+}
+
+// hoistSharedCTEs attaches every CTE materialized while building this
+// statement onto the outermost statement's WITH clause, in the topological
+// order they were first built in (a shared subtree can only depend on
+// subtrees that were built - and therefore appended - before it).
+func (qb *queryBuilder) hoistSharedCTEs() {
+	if qb.cteMemo == nil {
+		return
+	}
+	ctes := qb.cteMemo.orderedCTEs()
+	if len(ctes) == 0 {
+		return
+	}
+	var with **sqlparser.With
+	switch stmt := qb.stmt.(type) {
+	case *sqlparser.Select:
+		with = &stmt.With
+	case *sqlparser.Union:
+		with = &stmt.With
+	default:
+		return
+	}
+	if *with == nil {
+		*with = &sqlparser.With{}
+	}
+	(*with).CTEs = append(ctes, (*with).CTEs...)
+}
+
+// subplanMemo tracks operator subtrees that are reachable from more than one
+// parent in the operator DAG (e.g. a subquery reused on both sides of a
+// self-join). When such a subtree is large enough to be worth sharing, it is
+// hoisted once into a WITH-clause CTE instead of being re-emitted as SQL at
+// every reference site.
+type subplanMemo struct {
+	// refCount is the number of distinct parents that reach a given operator,
+	// keyed by a canonical structural hash of the subtree.
+	refCount map[string]int
+	// built holds the CommonTableExpr already emitted for a hash, along with
+	// the order in which it was first built so nested CTEs can be emitted in
+	// topological order.
+	built map[string]*sqlparser.CommonTableExpr
+	order []string
+	// names hands out a stable, unique CTE name the first time a hash is seen.
+	names map[string]string
+	next  int
+}
+
+// minSharedSubplanCost is the minimum number of operators a shared subtree
+// must contain before it is worth hoisting into its own CTE. Small subtrees
+// (e.g. a single table scan) are cheaper to just duplicate in the SQL.
+const minSharedSubplanCost = 3
+
+func newSubplanMemo() *subplanMemo {
+	return &subplanMemo{
+		refCount: map[string]int{},
+		built:    map[string]*sqlparser.CommonTableExpr{},
+		names:    map[string]string{},
+	}
+}
+
+// countRefs walks the operator DAG once, computing a canonical hash for
+// every subtree and counting how many times each distinct hash is reached.
+// A hash reached more than once means the same subplan is referenced from
+// multiple parents and is a candidate for CTE hoisting.
+func (m *subplanMemo) countRefs(op Operator) string {
+	hash := canonicalOperatorHash(op)
+	m.refCount[hash]++
+	for _, child := range operatorChildren(op) {
+		m.countRefs(child)
+	}
+	return hash
+}
+
+// shared reports whether the subtree rooted at op is referenced from more
+// than one place and is large enough to be worth materializing as a CTE.
+func (m *subplanMemo) shared(op Operator) bool {
+	hash := canonicalOperatorHash(op)
+	return m.refCount[hash] > 1 && operatorSize(op) >= minSharedSubplanCost
+}
+
+// cteNameFor returns the synthesized CTE name for the subtree rooted at op,
+// allocating one the first time it is asked for.
+func (m *subplanMemo) cteNameFor(op Operator) string {
+	hash := canonicalOperatorHash(op)
+	if name, ok := m.names[hash]; ok {
+		return name
+	}
+	name := fmt.Sprintf("cte_%d", m.next)
+	m.next++
+	m.names[hash] = name
+	return name
+}
+
+// markBuilt records the CommonTableExpr produced for the subtree rooted at
+// op the first time it is visited, so later visits can just reference it by
+// name instead of re-emitting the SQL.
+func (m *subplanMemo) markBuilt(op Operator, cte *sqlparser.CommonTableExpr) {
+	hash := canonicalOperatorHash(op)
+	if _, ok := m.built[hash]; ok {
+		return
+	}
+	m.built[hash] = cte
+	m.order = append(m.order, hash)
+}
+
+// builtCTE returns the CommonTableExpr previously built for the subtree
+// rooted at op, if any.
+func (m *subplanMemo) builtCTE(op Operator) (*sqlparser.CommonTableExpr, bool) {
+	cte, ok := m.built[canonicalOperatorHash(op)]
+	return cte, ok
+}
+
+// orderedCTEs returns the CTEs built so far in the order they were first
+// materialized, which is also a valid topological order since a subtree can
+// only be built after all of the subtrees it depends on.
+func (m *subplanMemo) orderedCTEs() []*sqlparser.CommonTableExpr {
+	out := make([]*sqlparser.CommonTableExpr, 0, len(m.order))
+	for _, hash := range m.order {
+		out = append(out, m.built[hash])
+	}
+	return out
+}
+
+// operatorChildren returns the direct children of op, using the same field
+// names buildQuery already switches on.
+func operatorChildren(op Operator) []Operator {
+	switch op := op.(type) {
+	case *Table:
+		return nil
+	case *Projection:
+		return []Operator{op.Source}
+	case *ApplyJoin:
+		return []Operator{op.LHS, op.RHS}
+	case *Filter:
+		return []Operator{op.Source}
+	case *Horizon:
+		return []Operator{op.Source}
+	case *Limit:
+		return []Operator{op.Source}
+	case *Ordering:
+		return []Operator{op.Source}
+	case *Aggregator:
+		return []Operator{op.Source}
+	case *Window:
+		return []Operator{op.Source}
+	case *Union:
+		return op.Sources
+	case *Distinct:
+		return []Operator{op.Source}
+	case *RecurseCTE:
+		return []Operator{op.Seed(), op.Term()}
+	default:
+		return nil
+	}
+}
+
+// operatorSize returns the number of operators in the subtree rooted at op,
+// used as a cheap proxy for how expensive it would be to duplicate the
+// subtree's SQL instead of hoisting it into a CTE.
+func operatorSize(op Operator) int {
+	size := 1
+	for _, child := range operatorChildren(op) {
+		size += operatorSize(child)
+	}
+	return size
+}
+
+// canonicalOperatorHash produces a structural fingerprint of the subtree
+// rooted at op that is insensitive to which Go pointer instance is used,
+// so that two equivalent subtrees (e.g. a subquery planned twice on either
+// side of a join) hash identically. Beyond the Go type of each node, it
+// folds in whatever identifies *this* node's data - the base table and its
+// predicates for a *Table, the predicate text for a *Filter, and so on -
+// so that two subtrees with the same shape but different tables or
+// predicates (e.g. two unrelated *Filter(*Table) leaves in a self-join)
+// are never mistaken for the same subplan.
+func canonicalOperatorHash(op Operator) string {
+	if op == nil {
+		return "nil"
+	}
+	children := operatorChildren(op)
+	h := fmt.Sprintf("%T", op) + "{" + operatorIdentity(op) + "}"
+	for _, child := range children {
+		h += "(" + canonicalOperatorHash(child) + ")"
+	}
+	return h
+}
+
+// operatorIdentity returns a string capturing the parts of op that make it
+// semantically distinct from another node of the same Go type, e.g. which
+// table it scans or which predicates/columns it applies. Operators with no
+// node-local data to distinguish (their identity is fully captured by their
+// children, e.g. *Limit or *Distinct) return an empty string.
+func operatorIdentity(op Operator) string {
+	switch op := op.(type) {
+	case *Table:
+		return fmt.Sprintf("%s:%s:%s", op.QTable.Table.Name.String(), op.QTable.Alias.As.String(), exprsHash(op.QTable.Predicates))
+	case *Filter:
+		return exprsHash(op.Predicates)
+	case *Projection:
+		return fmt.Sprintf("%v", TableID(op))
+	case *Aggregator:
+		return fmt.Sprintf("%v", TableID(op))
+	case *Horizon:
+		return fmt.Sprintf("%v", TableID(op))
+	case *Ordering:
+		exprs := make(sqlparser.Exprs, 0, len(op.Order))
+		for _, order := range op.Order {
+			exprs = append(exprs, order.Inner.Expr)
+		}
+		return exprsHash(exprs)
+	case *ApplyJoin:
+		return fmt.Sprintf("%v:%v", op.LeftJoin, TableID(op))
+	case *Union:
+		return fmt.Sprintf("%v", op.distinct)
+	default:
+		return ""
+	}
+}
+
+// exprsHash renders a slice of expressions into a stable string, used as
+// part of an operator's identity so that two otherwise identically-shaped
+// nodes with different predicates or order-by keys never collide.
+func exprsHash(exprs sqlparser.Exprs) string {
+	parts := make([]string, 0, len(exprs))
+	for _, expr := range exprs {
+		parts = append(parts, sqlparser.String(expr))
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operators
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vitess.io/vitess/go/vt/sqlparser"
+)
+
+func filterOverTable(t *testing.T, tableName string, predicate string) *Filter {
+	t.Helper()
+	qt := &QueryTable{
+		Table: sqlparser.TableName{Name: sqlparser.NewIdentifierCS(tableName)},
+		Alias: sqlparser.NewAliasedTableExpr(sqlparser.NewTableName(tableName), ""),
+	}
+	if predicate != "" {
+		expr, err := sqlparser.NewTestParser().ParseExpr(predicate)
+		require.NoError(t, err)
+		qt.Predicates = []sqlparser.Expr{expr}
+	}
+	return &Filter{Source: &Table{QTable: qt}}
+}
+
+// TestCanonicalOperatorHashDistinguishesTables asserts that two structurally
+// identical *Filter(*Table) subtrees over different base tables (e.g. the two
+// sides of a self-join) never collide, since the shared-subplan memo would
+// otherwise merge them and silently swap one side's rows for the other's.
+func TestCanonicalOperatorHashDistinguishesTables(t *testing.T) {
+	left := filterOverTable(t, "users", "users.active = 1")
+	right := filterOverTable(t, "orders", "orders.active = 1")
+
+	assert.NotEqual(t, canonicalOperatorHash(left), canonicalOperatorHash(right),
+		"same-shaped subtrees over different tables must not hash the same")
+}
+
+// TestCanonicalOperatorHashDistinguishesPredicates asserts that two filters
+// over the same table but with different predicates hash differently, since
+// they are not interchangeable subplans even though their shape and base
+// table are identical.
+func TestCanonicalOperatorHashDistinguishesPredicates(t *testing.T) {
+	left := filterOverTable(t, "users", "users.active = 1")
+	right := filterOverTable(t, "users", "users.active = 0")
+
+	assert.NotEqual(t, canonicalOperatorHash(left), canonicalOperatorHash(right),
+		"same table with different predicates must not hash the same")
+}
+
+// TestCanonicalOperatorHashStable asserts that two independently-built
+// subtrees that really are equivalent - same table, same predicate - do
+// still hash identically, which is what makes them a valid candidate for CTE
+// sharing in the first place.
+func TestCanonicalOperatorHashStable(t *testing.T) {
+	a := filterOverTable(t, "users", "users.active = 1")
+	b := filterOverTable(t, "users", "users.active = 1")
+
+	assert.Equal(t, canonicalOperatorHash(a), canonicalOperatorHash(b))
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operators
+
+import (
+	"fmt"
+
+	"vitess.io/vitess/go/vt/sqlparser"
+	"vitess.io/vitess/go/vt/vterrors"
+)
+
+// ExprKind identifies which clause of the emitted SQL an expression is
+// being added to, so a single validator can decide whether the expression
+// is allowed to contain things like aggregates, window functions, or
+// subqueries in that position.
+type ExprKind int
+
+const (
+	ExprKindWhere ExprKind = iota
+	ExprKindHaving
+	ExprKindGroupBy
+	ExprKindOrderBy
+	ExprKindJoinOn
+	ExprKindColumnDefault
+	ExprKindSelectTarget
+	ExprKindLimit
+	ExprKindUpdateSet
+)
+
+func (k ExprKind) String() string {
+	switch k {
+	case ExprKindWhere:
+		return "WHERE"
+	case ExprKindHaving:
+		return "HAVING"
+	case ExprKindGroupBy:
+		return "GROUP BY"
+	case ExprKindOrderBy:
+		return "ORDER BY"
+	case ExprKindJoinOn:
+		return "ON"
+	case ExprKindColumnDefault:
+		return "DEFAULT"
+	case ExprKindSelectTarget:
+		return "SELECT"
+	case ExprKindLimit:
+		return "LIMIT"
+	case ExprKindUpdateSet:
+		return "SET"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// exprKindRules says, for a given ExprKind, which categories of expression
+// are permitted in that position.
+type exprKindRules struct {
+	aggregates   bool
+	windowFuncs  bool
+	subqueries   bool
+	setReturning bool
+}
+
+var kindAllows = map[ExprKind]exprKindRules{
+	ExprKindWhere:         {aggregates: false, windowFuncs: false, subqueries: true, setReturning: false},
+	ExprKindHaving:        {aggregates: true, windowFuncs: false, subqueries: true, setReturning: false},
+	ExprKindGroupBy:       {aggregates: false, windowFuncs: false, subqueries: true, setReturning: false},
+	ExprKindOrderBy:       {aggregates: true, windowFuncs: true, subqueries: true, setReturning: false},
+	ExprKindJoinOn:        {aggregates: false, windowFuncs: false, subqueries: true, setReturning: false},
+	ExprKindColumnDefault: {aggregates: false, windowFuncs: false, subqueries: false, setReturning: false},
+	ExprKindSelectTarget:  {aggregates: true, windowFuncs: true, subqueries: true, setReturning: true},
+	ExprKindLimit:         {aggregates: false, windowFuncs: false, subqueries: false, setReturning: false},
+	ExprKindUpdateSet:     {aggregates: false, windowFuncs: false, subqueries: true, setReturning: false},
+}
+
+// withExprKind validates expr against the rules for kind, then temporarily
+// sets qb's active expression kind for the duration of fn, restoring the
+// previous kind afterwards. addPredicate, addGroupBy, addProjection, joinWith
+// and buildOrdering wrap their additions in withExprKind so misplaced
+// aggregates, window functions, or subqueries are rejected with a precise
+// error naming the clause, instead of silently producing invalid SQL or
+// being caught ad hoc via ctx.ContainsAggr. buildLimit and buildUpdate call
+// validateExprKind directly and leave qb.exprKind unchanged.
+func (qb *queryBuilder) withExprKind(kind ExprKind, expr sqlparser.Expr, fn func()) {
+	validateExprKind(kind, expr)
+
+	prev := qb.exprKind
+	qb.exprKind = kind
+	defer func() { qb.exprKind = prev }()
+	fn()
+}
+
+// validateExprKind walks expr once, looking for constructs that aren't
+// allowed in the clause identified by kind, and panics with a precise
+// VT12001 error naming both the offending construct and the clause.
+func validateExprKind(kind ExprKind, expr sqlparser.Expr) {
+	if expr == nil {
+		return
+	}
+	rules := kindAllows[kind]
+
+	_ = sqlparser.Walk(func(node sqlparser.SQLNode) (bool, error) {
+		switch node := node.(type) {
+		case sqlparser.AggrFunc:
+			if !rules.aggregates {
+				panic(vterrors.VT12001(fmt.Sprintf("aggregate functions not allowed in %s", kind)))
+			}
+		case *sqlparser.Subquery:
+			if !rules.subqueries {
+				panic(vterrors.VT12001(fmt.Sprintf("subqueries not allowed in %s", kind)))
+			}
+			// Don't descend into the subquery's own SELECT: its internal
+			// aggregates/window functions/GROUP BY/HAVING are scoped to the
+			// subquery itself, not to kind's clause, and judging them against
+			// kind's rules would reject perfectly legal queries such as
+			// `WHERE id IN (SELECT user_id FROM orders GROUP BY user_id HAVING COUNT(*) > 1)`.
+			return false, nil
+		case *sqlparser.FuncExpr:
+			if node.Over != nil && !rules.windowFuncs {
+				panic(vterrors.VT12001(fmt.Sprintf("window functions not allowed in %s", kind)))
+			}
+		}
+		return true, nil
+	}, expr)
+}
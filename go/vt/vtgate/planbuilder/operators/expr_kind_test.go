@@ -0,0 +1,66 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operators
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vitess.io/vitess/go/vt/sqlparser"
+)
+
+// TestValidateExprKindRejectsMisplacedAggregate asserts that an aggregate
+// function is rejected in clauses that don't allow it (a JOIN...ON
+// condition, WHERE and GROUP BY), mirroring the real restrictions MySQL itself
+// enforces, and is accepted where it is legal (HAVING, SELECT targets).
+func TestValidateExprKindRejectsMisplacedAggregate(t *testing.T) {
+	parser := sqlparser.NewTestParser()
+	expr, err := parser.ParseExpr("count(*) > 1")
+	require.NoError(t, err)
+
+	assert.Panics(t, func() { validateExprKind(ExprKindJoinOn, expr) },
+		"aggregate in an ON condition must be rejected")
+	assert.Panics(t, func() { validateExprKind(ExprKindWhere, expr) },
+		"aggregate in a WHERE clause must be rejected")
+	assert.Panics(t, func() { validateExprKind(ExprKindGroupBy, expr) },
+		"aggregate in a GROUP BY clause must be rejected")
+
+	assert.NotPanics(t, func() { validateExprKind(ExprKindHaving, expr) },
+		"aggregate is legal in HAVING")
+	assert.NotPanics(t, func() { validateExprKind(ExprKindSelectTarget, expr) },
+		"aggregate is legal in a SELECT target")
+}
+
+// TestValidateExprKindIgnoresAggregatesScopedInsideSubquery asserts that an aggregate or window
+// function inside a subquery's own SELECT/GROUP BY/HAVING is never judged against the outer
+// clause's rules: it's scoped to the subquery, not to kind's clause, so even a WHERE/ON (which
+// disallow aggregates at their own level) must accept a subquery containing one.
+func TestValidateExprKindIgnoresAggregatesScopedInsideSubquery(t *testing.T) {
+	parser := sqlparser.NewTestParser()
+
+	whereExpr, err := parser.ParseExpr("id in (select user_id from orders group by user_id having count(*) > 1)")
+	require.NoError(t, err)
+	assert.NotPanics(t, func() { validateExprKind(ExprKindWhere, whereExpr) },
+		"an aggregate scoped inside a subquery's own HAVING must not be rejected by the outer WHERE's rules")
+
+	onExpr, err := parser.ParseExpr("x = (select max(y) from t2)")
+	require.NoError(t, err)
+	assert.NotPanics(t, func() { validateExprKind(ExprKindJoinOn, onExpr) },
+		"an aggregate scoped inside a subquery's own SELECT must not be rejected by the outer ON's rules")
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operators
+
+// OpCursor describes an operator encountered during Rewrite, along with
+// enough context to replace it, remove it, or insert a sibling before it,
+// mirroring the cursor used by tree-rewriting packages like sqlc's
+// astutils.Apply.
+type OpCursor struct {
+	node    Operator
+	parent  Operator
+	replace func(Operator)
+	deleted bool
+	before  []Operator
+}
+
+// Node returns the operator the cursor currently points at.
+func (c *OpCursor) Node() Operator { return c.node }
+
+// Parent returns the operator that owns this node, or nil at the root.
+func (c *OpCursor) Parent() Operator { return c.parent }
+
+// Replace swaps the current node for a new one.
+func (c *OpCursor) Replace(op Operator) {
+	c.node = op
+	if c.replace != nil {
+		c.replace(op)
+	}
+}
+
+// Delete marks the current node for removal from its parent. Only valid
+// for a Union source; deleting the sole child of a single-source operator
+// or one side of an ApplyJoin panics, and Rewrite ignores a Delete of the
+// root.
+func (c *OpCursor) Delete() {
+	c.deleted = true
+}
+
+// InsertBefore queues op to be inserted as a sibling immediately before the
+// current node under the same parent. Only meaningful for multi-child
+// operators such as Union.
+func (c *OpCursor) InsertBefore(op Operator) {
+	c.before = append(c.before, op)
+}
+
+// RewriteFunc is called once per node during a Rewrite pass. Returning false
+// stops the traversal from descending into this node's children (pre) or
+// from continuing to later siblings (post).
+type RewriteFunc func(*OpCursor) bool
+
+// Rewrite walks the operator DAG rooted at root, calling pre before
+// descending into a node's children and post after, giving each callback a
+// chance to Replace, Delete, or InsertBefore via the supplied OpCursor.
+// Either callback may be nil.
+func Rewrite(root Operator, pre, post RewriteFunc) Operator {
+	cur := &OpCursor{node: root}
+	rewriteChildren(cur, pre, post)
+	return cur.node
+}
+
+func rewriteChildren(cur *OpCursor, pre, post RewriteFunc) bool {
+	if pre != nil && !pre(cur) {
+		return true
+	}
+
+	children := operatorChildren(cur.node)
+	if len(children) > 0 {
+		newChildren := make([]Operator, 0, len(children))
+		for i, child := range children {
+			childCur := &OpCursor{node: child, parent: cur.node}
+			cont := rewriteChildren(childCur, pre, post)
+			if !childCur.deleted {
+				newChildren = append(newChildren, childCur.before...)
+				newChildren = append(newChildren, childCur.node)
+			}
+			if !cont {
+				newChildren = append(newChildren, children[i+1:]...)
+				break
+			}
+		}
+		setOperatorChildren(cur.node, newChildren)
+	}
+
+	if post != nil {
+		return post(cur)
+	}
+	return true
+}
+
+// setOperatorChildren writes back the (possibly rewritten) children of op,
+// mirroring the field layout operatorChildren reads from.
+func setOperatorChildren(op Operator, children []Operator) {
+	switch op := op.(type) {
+	case *Projection:
+		op.Source = children[0]
+	case *ApplyJoin:
+		op.LHS, op.RHS = children[0], children[1]
+	case *Filter:
+		op.Source = children[0]
+	case *Horizon:
+		op.Source = children[0]
+	case *Limit:
+		op.Source = children[0]
+	case *Ordering:
+		op.Source = children[0]
+	case *Aggregator:
+		op.Source = children[0]
+	case *Window:
+		op.Source = children[0]
+	case *Union:
+		op.Sources = children
+	case *Distinct:
+		op.Source = children[0]
+	}
+}
+
+// rewritePhase identifies where in the planning pipeline a RewriteRule runs.
+type rewritePhase string
+
+const (
+	// PhasePostPhysical runs once physical planning has produced a final
+	// operator tree, before it is handed to ToSQL for SQL emission.
+	PhasePostPhysical rewritePhase = "post-physical"
+)
+
+// RewriteRule is a named, pluggable transformation over the operator DAG.
+type RewriteRule struct {
+	Name string
+	Fn   RewriteFunc
+}
+
+var rewriteRules = map[rewritePhase][]RewriteRule{}
+
+// RegisterRewriteRule adds fn to the chain of rules run for phase, so that
+// downstream forks and tools can inject operator-tree transformations
+// without patching buildQuery directly.
+func RegisterRewriteRule(phase rewritePhase, name string, fn RewriteFunc) {
+	rewriteRules[phase] = append(rewriteRules[phase], RewriteRule{Name: name, Fn: fn})
+}
+
+// applyRewriteRules runs every rule registered for phase, in registration
+// order, over the operator tree rooted at op.
+func applyRewriteRules(phase rewritePhase, op Operator) Operator {
+	for _, rule := range rewriteRules[phase] {
+		op = Rewrite(op, rule.Fn, nil)
+	}
+	return op
+}
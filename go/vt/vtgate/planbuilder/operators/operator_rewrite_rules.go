@@ -0,0 +1,80 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operators
+
+import "vitess.io/vitess/go/vt/sqlparser"
+
+func init() {
+	RegisterRewriteRule(PhasePostPhysical, "mergeStackedFilters", mergeStackedFilters)
+	RegisterRewriteRule(PhasePostPhysical, "dropRedundantHorizon", dropRedundantHorizon)
+}
+
+// mergeStackedFilters collapses a Filter directly on top of another Filter
+// into a single Filter holding both sides' predicates, in outer-then-inner
+// order. Two such Filters can end up stacked after earlier planning phases
+// each add their own predicate-pushdown Filter without checking whether the
+// operator immediately below is already one, and emitting them as nested
+// SQL predicates is equivalent to emitting them as one flattened list.
+func mergeStackedFilters(cur *OpCursor) bool {
+	outer, ok := cur.Node().(*Filter)
+	if !ok {
+		return true
+	}
+	source, predicates := outer.Source, outer.Predicates
+	for {
+		inner, ok := source.(*Filter)
+		if !ok {
+			break
+		}
+		source = inner.Source
+		predicates = append(predicates, inner.Predicates...)
+	}
+	if source != outer.Source {
+		cur.Replace(&Filter{Source: source, Predicates: predicates})
+	}
+	return true
+}
+
+// dropRedundantHorizon removes a Horizon whose Query is nothing but a bare
+// `SELECT * FROM <source>` - no WHERE/HAVING/GROUP BY/ORDER BY/LIMIT/
+// DISTINCT and no column aliases of its own. buildHorizon would otherwise
+// emit this Horizon as a derived-table wrapper around its source even
+// though it adds nothing beyond what the source already produces, the
+// same redundancy collapseRedundantDerivedTables prunes from the final
+// SQL text; pruning it here means buildHorizon never has to special-case
+// it in the first place.
+func dropRedundantHorizon(cur *OpCursor) bool {
+	horizon, ok := cur.Node().(*Horizon)
+	if !ok {
+		return true
+	}
+	sel, ok := horizon.Query.(*sqlparser.Select)
+	if !ok {
+		return true
+	}
+	if len(horizon.ColumnAliases) > 0 {
+		return true
+	}
+	if sel.Where != nil || sel.Having != nil || len(sel.GroupBy.Columns) > 0 || len(sel.OrderBy) > 0 || !sel.Limit.IsEmpty() || sel.Distinct {
+		return true
+	}
+	if !isTrivialProjection(sel.SelectExprs) {
+		return true
+	}
+	cur.Replace(horizon.Source)
+	return true
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operators
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vitess.io/vitess/go/vt/sqlparser"
+)
+
+func predicate(t *testing.T, expr string) sqlparser.Expr {
+	t.Helper()
+	parsed, err := sqlparser.NewTestParser().ParseExpr(expr)
+	require.NoError(t, err)
+	return parsed
+}
+
+// TestMergeStackedFilters proves the registered mergeStackedFilters rule actually runs: calling
+// Rewrite with the PhasePostPhysical rules registered on init collapses a chain of stacked
+// Filters into one, rather than RegisterRewriteRule/Rewrite existing with no rule ever exercised.
+func TestMergeStackedFilters(t *testing.T) {
+	table := &Table{QTable: &QueryTable{Table: sqlparser.TableName{Name: sqlparser.NewIdentifierCS("t")}}}
+	stacked := &Filter{
+		Predicates: []sqlparser.Expr{predicate(t, "a = 1")},
+		Source: &Filter{
+			Predicates: []sqlparser.Expr{predicate(t, "b = 2")},
+			Source: &Filter{
+				Predicates: []sqlparser.Expr{predicate(t, "c = 3")},
+				Source:     table,
+			},
+		},
+	}
+
+	rewritten := applyRewriteRules(PhasePostPhysical, stacked)
+
+	merged, ok := rewritten.(*Filter)
+	require.True(t, ok)
+	assert.Len(t, merged.Predicates, 3)
+	assert.Same(t, Operator(table), merged.Source)
+}
+
+// TestMergeStackedFiltersLeavesUnstackedFiltersAlone proves the rule is a no-op when there's
+// nothing to merge, so it never disturbs a tree it doesn't apply to.
+func TestMergeStackedFiltersLeavesUnstackedFiltersAlone(t *testing.T) {
+	table := &Table{QTable: &QueryTable{Table: sqlparser.TableName{Name: sqlparser.NewIdentifierCS("t")}}}
+	single := &Filter{Predicates: []sqlparser.Expr{predicate(t, "a = 1")}, Source: table}
+
+	rewritten := applyRewriteRules(PhasePostPhysical, single)
+
+	assert.Same(t, Operator(single), rewritten)
+}
+
+func selectStar(t *testing.T) *sqlparser.Select {
+	t.Helper()
+	parser := sqlparser.NewTestParser()
+	stmt, err := parser.Parse("select * from dual")
+	require.NoError(t, err)
+	return stmt.(*sqlparser.Select)
+}
+
+// TestDropRedundantHorizonRemovesPassThroughWrapper proves the registered dropRedundantHorizon
+// rule actually runs: a Horizon whose Query is just `SELECT *`, with no filtering, grouping,
+// ordering, limit, distinct, or column aliases of its own, is spliced out in favor of its source.
+func TestDropRedundantHorizonRemovesPassThroughWrapper(t *testing.T) {
+	table := &Table{QTable: &QueryTable{Table: sqlparser.TableName{Name: sqlparser.NewIdentifierCS("t")}}}
+	horizon := &Horizon{Source: table, Query: selectStar(t)}
+
+	rewritten := applyRewriteRules(PhasePostPhysical, horizon)
+
+	assert.Same(t, Operator(table), rewritten)
+}
+
+// TestDropRedundantHorizonLeavesNonTrivialHorizonAlone proves a Horizon whose Query adds
+// something of its own - here a WHERE clause - is never dropped, since doing so would discard
+// that filtering entirely.
+func TestDropRedundantHorizonLeavesNonTrivialHorizonAlone(t *testing.T) {
+	parser := sqlparser.NewTestParser()
+	stmt, err := parser.Parse("select * from dual where x = 1")
+	require.NoError(t, err)
+
+	table := &Table{QTable: &QueryTable{Table: sqlparser.TableName{Name: sqlparser.NewIdentifierCS("t")}}}
+	horizon := &Horizon{Source: table, Query: stmt.(*sqlparser.Select)}
+
+	rewritten := applyRewriteRules(PhasePostPhysical, horizon)
+
+	assert.Same(t, Operator(horizon), rewritten)
+}
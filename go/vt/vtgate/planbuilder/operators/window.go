@@ -0,0 +1,76 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operators
+
+import (
+	"vitess.io/vitess/go/vt/sqlparser"
+	"vitess.io/vitess/go/vt/vterrors"
+)
+
+// Window is an operator representing a set of window function calls
+// (ROW_NUMBER(), RANK(), SUM() OVER (...), etc.) evaluated over a single
+// PARTITION BY / ORDER BY / frame specification, analogous to how
+// Aggregator represents a GROUP BY. Window functions are only legal in the
+// SELECT and ORDER BY clauses, never in WHERE/GROUP BY/HAVING - see
+// kindAllows in expr_kind.go.
+type Window struct {
+	Source Operator
+
+	// Funcs are the window function calls sharing this Window's spec.
+	Funcs []*sqlparser.FuncExpr
+	// Spec is the PARTITION BY / ORDER BY / frame shared by every func in
+	// Funcs. Distinct OVER specs on the same source are planned as
+	// separate Window operators stacked on top of one another.
+	Spec *sqlparser.WindowSpecification
+	// Name is the synthesized name the spec is registered under on the
+	// emitted sqlparser.Select, e.g. "w0", referenced by OVER (w0) on the
+	// function calls that share it.
+	Name string
+}
+
+// addWindowSpec registers a named window specification on the statement
+// being built, so projections can reference it by name with OVER (name)
+// instead of repeating the PARTITION BY/ORDER BY/frame inline.
+func (qb *queryBuilder) addWindowSpec(name string, spec *sqlparser.WindowSpecification) {
+	sel, ok := qb.stmt.(*sqlparser.Select)
+	if !ok {
+		panic(vterrors.VT13001("window functions can only be emitted into a SELECT statement"))
+	}
+	spec.Name = sqlparser.NewIdentifierCI(name)
+	sel.Window = append(sel.Window, spec)
+}
+
+// addWindowedProjection adds fn as a SELECT target, wrapping it with
+// OVER (overRef) so it is emitted as a window function call rather than a
+// plain scalar/aggregate one.
+func (qb *queryBuilder) addWindowedProjection(fn *sqlparser.FuncExpr, overRef string) {
+	qb.withExprKind(ExprKindSelectTarget, fn, func() {
+		fn.Over = &sqlparser.OverClause{
+			WindowName: sqlparser.NewIdentifierCI(overRef),
+		}
+		qb.addProjection(&sqlparser.AliasedExpr{Expr: fn})
+	})
+}
+
+func buildWindow(op *Window, qb *queryBuilder) {
+	buildQuery(op.Source, qb)
+
+	qb.addWindowSpec(op.Name, op.Spec)
+	for _, fn := range op.Funcs {
+		qb.addWindowedProjection(fn, op.Name)
+	}
+}
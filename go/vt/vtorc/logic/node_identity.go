@@ -0,0 +1,45 @@
+/*
+   Copyright 2015 Shlomi Noach, courtesy Booking.com
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package logic
+
+import (
+	"os"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+var (
+	processIdentityOnce    sync.Once
+	processingNodeHostname string
+	processingNodeToken    string
+)
+
+// thisProcessIdentity returns a (hostname, token) pair identifying this VTOrc process.
+// The token is generated once per process lifetime, so a restarted VTOrc on the same host
+// is treated as a different owner than the one that crashed.
+func thisProcessIdentity() (string, string) {
+	processIdentityOnce.Do(func() {
+		hostname, err := os.Hostname()
+		if err != nil {
+			hostname = "unknown"
+		}
+		processingNodeHostname = hostname
+		processingNodeToken = uuid.NewString()
+	})
+	return processingNodeHostname, processingNodeToken
+}
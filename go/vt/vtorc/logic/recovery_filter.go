@@ -0,0 +1,119 @@
+/*
+   Copyright 2015 Shlomi Noach, courtesy Booking.com
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package logic
+
+import (
+	"fmt"
+	"strings"
+
+	"vitess.io/vitess/go/vt/vtorc/inst"
+)
+
+// recoveryOrderByColumns whitelists the columns a RecoveryFilter may sort by, so OrderBy
+// (which ultimately comes from an HTTP query parameter) can never be used to inject
+// arbitrary SQL.
+var recoveryOrderByColumns = map[string]string{
+	"":            "recovery_id DESC",
+	"recovery_id": "recovery_id DESC",
+	"duration":    "(JULIANDAY(IFNULL(end_recovery, DATETIME('now'))) - JULIANDAY(start_recovery)) DESC",
+}
+
+// RecoveryFilter narrows down a readRecoveries/readFailureDetections query so dashboards can
+// render per-keyspace failover histories without pulling the full audit table and filtering
+// client-side.
+type RecoveryFilter struct {
+	Keyspace       string
+	Shard          string
+	AnalysisCodes  []inst.AnalysisCode
+	SuccessorAlias string
+	IsSuccessful   *bool
+	StartedAfter   string
+	StartedBefore  string
+	UIDPrefix      string
+	OrderBy        string
+}
+
+// buildWhere turns the filter into a parameterized WHERE clause (or "" if the filter is
+// empty) plus its bind args, and the whitelisted ORDER BY clause to use, for a query against
+// topology_recovery. SuccessorAlias and IsSuccessful are only ever applied here: that table
+// is the only one of the two RecoveryFilter is used against that has those columns at all.
+// Queries against topology_failure_detection must use buildWhereForDetections instead.
+func (filter RecoveryFilter) buildWhere(startColumn string) (whereClause string, args []any, orderBy string) {
+	return filter.buildWhereConditions(startColumn, true)
+}
+
+// buildWhereForDetections is buildWhere for a query against topology_failure_detection,
+// which has no successor_alias or is_successful columns. SuccessorAlias/IsSuccessful set on
+// filter are silently ignored here rather than being turned into a WHERE clause that would
+// fail at query time against a table that doesn't have those columns.
+func (filter RecoveryFilter) buildWhereForDetections(startColumn string) (whereClause string, args []any, orderBy string) {
+	return filter.buildWhereConditions(startColumn, false)
+}
+
+// buildWhereConditions is the shared implementation behind buildWhere and
+// buildWhereForDetections; includeRecoveryOnlyColumns gates the conditions that only apply
+// to topology_recovery.
+func (filter RecoveryFilter) buildWhereConditions(startColumn string, includeRecoveryOnlyColumns bool) (whereClause string, args []any, orderBy string) {
+	var conditions []string
+	if filter.Keyspace != "" {
+		conditions = append(conditions, "keyspace = ?")
+		args = append(args, filter.Keyspace)
+	}
+	if filter.Shard != "" {
+		conditions = append(conditions, "shard = ?")
+		args = append(args, filter.Shard)
+	}
+	if len(filter.AnalysisCodes) > 0 {
+		placeholders := make([]string, len(filter.AnalysisCodes))
+		for i, code := range filter.AnalysisCodes {
+			placeholders[i] = "?"
+			args = append(args, string(code))
+		}
+		conditions = append(conditions, fmt.Sprintf("analysis IN (%s)", strings.Join(placeholders, ", ")))
+	}
+	if includeRecoveryOnlyColumns {
+		if filter.SuccessorAlias != "" {
+			conditions = append(conditions, "successor_alias = ?")
+			args = append(args, filter.SuccessorAlias)
+		}
+		if filter.IsSuccessful != nil {
+			conditions = append(conditions, "is_successful = ?")
+			args = append(args, *filter.IsSuccessful)
+		}
+	}
+	if filter.StartedAfter != "" {
+		conditions = append(conditions, fmt.Sprintf("%s >= ?", startColumn))
+		args = append(args, filter.StartedAfter)
+	}
+	if filter.StartedBefore != "" {
+		conditions = append(conditions, fmt.Sprintf("%s <= ?", startColumn))
+		args = append(args, filter.StartedBefore)
+	}
+	if filter.UIDPrefix != "" {
+		conditions = append(conditions, "uid LIKE ?")
+		args = append(args, filter.UIDPrefix+"%")
+	}
+
+	if len(conditions) > 0 {
+		whereClause = fmt.Sprintf("WHERE %s", strings.Join(conditions, " AND "))
+	}
+	orderBy, ok := recoveryOrderByColumns[filter.OrderBy]
+	if !ok {
+		orderBy = recoveryOrderByColumns[""]
+	}
+	return whereClause, args, orderBy
+}
@@ -0,0 +1,59 @@
+/*
+   Copyright 2024 The Vitess Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package logic
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestBuildWhereForDetectionsDropsRecoveryOnlyColumns asserts that a filter with
+// SuccessorAlias/IsSuccessful set never emits a condition on those columns when building a
+// WHERE clause for topology_failure_detection, which has no such columns and would error out
+// at query time if asked to filter on them.
+func TestBuildWhereForDetectionsDropsRecoveryOnlyColumns(t *testing.T) {
+	successful := true
+	filter := RecoveryFilter{
+		Keyspace:       "ks",
+		SuccessorAlias: "zone1-0000000100",
+		IsSuccessful:   &successful,
+	}
+
+	whereClause, args, _ := filter.buildWhereForDetections("start_active_period")
+
+	assert.Contains(t, whereClause, "keyspace = ?")
+	assert.NotContains(t, whereClause, "successor_alias")
+	assert.NotContains(t, whereClause, "is_successful")
+	assert.Equal(t, []any{"ks"}, args)
+}
+
+// TestBuildWhereKeepsRecoveryOnlyColumns asserts the topology_recovery-facing buildWhere
+// still applies SuccessorAlias/IsSuccessful, since that table does have those columns.
+func TestBuildWhereKeepsRecoveryOnlyColumns(t *testing.T) {
+	successful := true
+	filter := RecoveryFilter{
+		SuccessorAlias: "zone1-0000000100",
+		IsSuccessful:   &successful,
+	}
+
+	whereClause, args, _ := filter.buildWhere("start_recovery")
+
+	assert.Contains(t, whereClause, "successor_alias = ?")
+	assert.Contains(t, whereClause, "is_successful = ?")
+	assert.Equal(t, []any{"zone1-0000000100", true}, args)
+}
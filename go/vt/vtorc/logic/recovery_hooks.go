@@ -0,0 +1,124 @@
+/*
+   Copyright 2015 Shlomi Noach, courtesy Booking.com
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package logic
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"vitess.io/vitess/go/vt/log"
+	"vitess.io/vitess/go/vt/vtorc/config"
+)
+
+// hookOutputTruncateSize bounds how much stdout/stderr from a single hook we keep around,
+// so a chatty notifier can't bloat the recovery step audit trail.
+const hookOutputTruncateSize = 4096
+
+// HookErrorPolicy controls what happens when a pre-failover hook fails.
+type HookErrorPolicy string
+
+const (
+	// HookErrorPolicyAbort aborts the recovery attempt if a pre-failover hook fails.
+	HookErrorPolicyAbort HookErrorPolicy = "abort"
+	// HookErrorPolicyContinue logs the failure but lets the recovery proceed regardless.
+	HookErrorPolicyContinue HookErrorPolicy = "continue"
+)
+
+// runFailoverHooks runs each configured hook command out-of-band (i.e. never inside a SQL
+// transaction) for the given recovery, substituting placeholders in each command, and
+// records every invocation as a structured RunHook step. It stops at the first failing
+// hook only when policy is HookErrorPolicyAbort.
+func runFailoverHooks(topologyRecovery *TopologyRecovery, hookType string, commands []string, policy HookErrorPolicy) error {
+	for _, rawCommand := range commands {
+		command := substituteHookPlaceholders(rawCommand, topologyRecovery)
+		var hookErr error
+		stepErr := topologyRecovery.Step(StepRunHook).
+			WithMetadata(map[string]string{
+				"hookType": hookType,
+				"command":  command,
+			}).
+			Run(func() error {
+				hookErr = executeHookCommand(command)
+				return hookErr
+			})
+		if stepErr != nil {
+			log.Errorf("runFailoverHooks: %s hook %q failed: %+v", hookType, command, stepErr)
+			if policy == HookErrorPolicyAbort {
+				return stepErr
+			}
+		}
+	}
+	return nil
+}
+
+// executeHookCommand runs a single shell command with the configured per-hook timeout,
+// truncating its captured output so it is safe to record as the step's error text.
+func executeHookCommand(command string) error {
+	timeout := time.Duration(config.HookTimeoutSeconds) * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "bash", "-c", command)
+	output, err := cmd.CombinedOutput()
+	if len(output) > hookOutputTruncateSize {
+		output = output[:hookOutputTruncateSize]
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// substituteHookPlaceholders replaces the documented {placeholder} tokens in a hook command
+// with values taken from the recovery being processed.
+func substituteHookPlaceholders(command string, topologyRecovery *TopologyRecovery) string {
+	analysisEntry := topologyRecovery.AnalysisEntry
+	replacer := strings.NewReplacer(
+		"{keyspace}", analysisEntry.AnalyzedKeyspace,
+		"{shard}", analysisEntry.AnalyzedShard,
+		"{failedAlias}", analysisEntry.AnalyzedInstanceAlias,
+		"{successorAlias}", topologyRecovery.SuccessorAlias,
+		"{analysis}", string(analysisEntry.Analysis),
+		"{recoveryUID}", topologyRecovery.UID,
+		"{isSuccessful}", strconv.FormatBool(topologyRecovery.IsSuccessful),
+	)
+	return replacer.Replace(command)
+}
+
+// runPreFailoverHooks runs config.PreFailoverProcesses ahead of a recovery attempt.
+func runPreFailoverHooks(topologyRecovery *TopologyRecovery) error {
+	return runFailoverHooks(topologyRecovery, "pre-failover", config.PreFailoverProcesses, config.PreFailoverHookErrorPolicy)
+}
+
+// runPostFailoverHooks runs the success or failure post-failover process list, depending on
+// the outcome recorded on topologyRecovery. Post-failover hooks never abort the recovery -
+// by the time they run the recovery has already been resolved - so failures are logged only.
+func runPostFailoverHooks(topologyRecovery *TopologyRecovery) {
+	commands := config.PostFailedFailoverProcesses
+	hookType := "post-failover-failed"
+	if topologyRecovery.IsSuccessful {
+		commands = config.PostSuccessfulFailoverProcesses
+		hookType = "post-failover-successful"
+	}
+	if err := runFailoverHooks(topologyRecovery, hookType, commands, HookErrorPolicyContinue); err != nil {
+		log.Error(err)
+	}
+}
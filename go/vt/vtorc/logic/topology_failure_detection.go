@@ -0,0 +1,231 @@
+/*
+   Copyright 2015 Shlomi Noach, courtesy Booking.com
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package logic
+
+import (
+	"fmt"
+
+	"vitess.io/vitess/go/vt/external/golib/sqlutils"
+	"vitess.io/vitess/go/vt/log"
+	"vitess.io/vitess/go/vt/vtorc/config"
+	"vitess.io/vitess/go/vt/vtorc/db"
+	"vitess.io/vitess/go/vt/vtorc/inst"
+)
+
+// FailureDetection represents an entry in the topology_failure_detection table. It tracks
+// the "active period" of a given failure analysis on a given shard, so that a flapping
+// primary which re-triggers the same analysis right after a completed failover does not
+// immediately cause another recovery to be attempted.
+type FailureDetection struct {
+	DetectionID             int64
+	UID                     string
+	AnalysisEntry           inst.ReplicationAnalysis
+	StartActivePeriod       string
+	EndActivePeriodUnixTime int64
+	InActivePeriod          bool
+	CountAffectedReplicas   int
+
+	ProcessingNodeHostname string
+	ProcessingNodeToken    string
+}
+
+// NewFailureDetection creates a new FailureDetection for the given analysis, opening its
+// active period.
+func NewFailureDetection(analysisEntry inst.ReplicationAnalysis) *FailureDetection {
+	return &FailureDetection{
+		AnalysisEntry:  analysisEntry,
+		InActivePeriod: true,
+	}
+}
+
+// ClusterCanRecover returns true if no active recovery and no active (unexpired) failure
+// detection period block a new recovery from being attempted on the given keyspace/shard
+// for the given analysis.
+func ClusterCanRecover(keyspace string, shard string, analysis inst.AnalysisCode) (bool, error) {
+	recoveries, err := ReadActiveClusterRecoveries(keyspace, shard)
+	if err != nil {
+		return false, err
+	}
+	if len(recoveries) > 0 {
+		return false, nil
+	}
+	blocked, err := isDetectionPeriodActive(keyspace, shard, analysis)
+	if err != nil {
+		return false, err
+	}
+	return !blocked, nil
+}
+
+// isDetectionPeriodActive returns true if there exists a still-active failure detection
+// period for the given keyspace/shard/analysis, within config.RecoveryPeriodBlockSeconds
+// of its last update.
+func isDetectionPeriodActive(keyspace string, shard string, analysis inst.AnalysisCode) (bool, error) {
+	blocked := false
+	query := `SELECT
+			detection_id
+		FROM
+			topology_failure_detection
+		WHERE
+			keyspace = ?
+			AND shard = ?
+			AND analysis = ?
+			AND in_active_period = 1
+			AND end_active_period_unixtime >= STRFTIME('%s', 'now') - ?
+		`
+	err := db.QueryVTOrc(query, sqlutils.Args(keyspace, shard, string(analysis), config.RecoveryPeriodBlockSeconds), func(m sqlutils.RowMap) error {
+		blocked = true
+		return nil
+	})
+	if err != nil {
+		log.Error(err)
+		return false, err
+	}
+	return blocked, nil
+}
+
+// writeFailureDetection inserts or refreshes the active period of a failure detection entry.
+func writeFailureDetection(failureDetection *FailureDetection) error {
+	analysisEntry := failureDetection.AnalysisEntry
+	hostname, token := thisProcessIdentity()
+	sqlResult, err := db.ExecVTOrc(`INSERT OR IGNORE
+		INTO topology_failure_detection (
+			uid,
+			alias,
+			analysis,
+			keyspace,
+			shard,
+			start_active_period,
+			end_active_period_unixtime,
+			in_active_period,
+			count_affected_replicas,
+			processing_node_hostname,
+			processing_node_token
+		) VALUES (
+			?,
+			?,
+			?,
+			?,
+			?,
+			DATETIME('now'),
+			STRFTIME('%s', 'now'),
+			1,
+			?,
+			?,
+			?
+		)`,
+		failureDetection.UID,
+		analysisEntry.AnalyzedInstanceAlias,
+		string(analysisEntry.Analysis),
+		analysisEntry.AnalyzedKeyspace,
+		analysisEntry.AnalyzedShard,
+		failureDetection.CountAffectedReplicas,
+		hostname,
+		token,
+	)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	failureDetection.DetectionID, err = sqlResult.LastInsertId()
+	if err != nil {
+		log.Error(err)
+	}
+	return err
+}
+
+// readFailureDetections reads failure detection audit entries from topology_failure_detection
+func readFailureDetections(whereCondition string, limit string, args []any) ([]*FailureDetection, error) {
+	res := []*FailureDetection{}
+	query := fmt.Sprintf(`SELECT
+			detection_id,
+			uid,
+			alias,
+			analysis,
+			keyspace,
+			shard,
+			start_active_period,
+			IFNULL(end_active_period_unixtime, 0) AS end_active_period_unixtime,
+			in_active_period,
+			count_affected_replicas
+		FROM
+			topology_failure_detection
+		%s
+		ORDER BY detection_id DESC
+		%s
+		`,
+		whereCondition,
+		limit,
+	)
+	err := db.QueryVTOrc(query, args, func(m sqlutils.RowMap) error {
+		failureDetection := &FailureDetection{}
+		failureDetection.DetectionID = m.GetInt64("detection_id")
+		failureDetection.UID = m.GetString("uid")
+		failureDetection.AnalysisEntry.AnalyzedInstanceAlias = m.GetString("alias")
+		failureDetection.AnalysisEntry.Analysis = inst.AnalysisCode(m.GetString("analysis"))
+		failureDetection.AnalysisEntry.AnalyzedKeyspace = m.GetString("keyspace")
+		failureDetection.AnalysisEntry.AnalyzedShard = m.GetString("shard")
+		failureDetection.StartActivePeriod = m.GetString("start_active_period")
+		failureDetection.EndActivePeriodUnixTime = m.GetInt64("end_active_period_unixtime")
+		failureDetection.InActivePeriod = m.GetBool("in_active_period")
+		failureDetection.CountAffectedReplicas = m.GetInt("count_affected_replicas")
+		res = append(res, failureDetection)
+		return nil
+	})
+	if err != nil {
+		log.Error(err)
+	}
+	return res, err
+}
+
+// ReadRecentFailureDetections reads the latest failure detection entries, narrowed down by
+// filter (the zero value matches everything) and paginated via config.AuditPageSize.
+func ReadRecentFailureDetections(filter RecoveryFilter, page int) ([]*FailureDetection, error) {
+	whereClause, args, _ := filter.buildWhereForDetections("start_active_period")
+	limit := `LIMIT ? OFFSET ?`
+	args = append(args, config.AuditPageSize, page*config.AuditPageSize)
+	return readFailureDetections(whereClause, limit, args)
+}
+
+// ReadBlockedRecoveries reads the failure detections that are currently within their active
+// period and would therefore block a new recovery via ClusterCanRecover.
+func ReadBlockedRecoveries() ([]*FailureDetection, error) {
+	whereClause := `WHERE
+		in_active_period = 1
+		AND end_active_period_unixtime >= STRFTIME('%s', 'now') - ?`
+	return readFailureDetections(whereClause, ``, sqlutils.Args(config.RecoveryPeriodBlockSeconds))
+}
+
+// AcknowledgeFailureDetection explicitly clears the active period for the failure detection
+// identified by uid, allowing a blocked recovery to proceed immediately instead of waiting
+// out RecoveryPeriodBlockSeconds. owner/comment are recorded for audit purposes.
+func AcknowledgeFailureDetection(uid string, owner string, comment string) error {
+	_, err := db.ExecVTOrc(`UPDATE topology_failure_detection
+		SET
+			in_active_period = 0,
+			end_active_period_unixtime = STRFTIME('%s', 'now')
+		WHERE
+			uid = ?
+		`,
+		uid,
+	)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	log.Infof("AcknowledgeFailureDetection: uid=%s acknowledged by %s: %s", uid, owner, comment)
+	return nil
+}
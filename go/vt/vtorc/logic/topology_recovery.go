@@ -0,0 +1,140 @@
+/*
+   Copyright 2015 Shlomi Noach, courtesy Booking.com
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package logic
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"vitess.io/vitess/go/vt/log"
+	"vitess.io/vitess/go/vt/vtorc/inst"
+)
+
+// TopologyRecovery represents an entry in the topology_recovery table
+type TopologyRecovery struct {
+	ID  int64
+	UID string
+
+	AnalysisEntry          inst.ReplicationAnalysis
+	SuccessorAlias         string
+	IsSuccessful           bool
+	AllErrors              []string
+	RecoveryStartTimestamp string
+	RecoveryEndTimestamp   string
+	DetectionID            int64
+
+	ProcessingNodeHostname string
+	ProcessingNodeToken    string
+}
+
+// NewTopologyRecovery creates a new instance of TopologyRecovery, generating it a
+// stable UID that remains valid even if the underlying recovery_id is later renumbered.
+func NewTopologyRecovery(replicationAnalysis inst.ReplicationAnalysis) *TopologyRecovery {
+	topologyRecovery := &TopologyRecovery{}
+	topologyRecovery.UID = uuid.NewString()
+	topologyRecovery.AnalysisEntry = replicationAnalysis
+	topologyRecovery.AllErrors = []string{}
+	return topologyRecovery
+}
+
+// StepType classifies what a TopologyRecoveryStep actually did, so post-mortem tooling
+// can build a timeline without regex-parsing free-form messages.
+type StepType string
+
+const (
+	StepDetect          StepType = "Detect"
+	StepElect           StepType = "Elect"
+	StepPromote         StepType = "Promote"
+	StepReparentReplica StepType = "ReparentReplica"
+	StepRunHook         StepType = "RunHook"
+	StepNotify          StepType = "Notify"
+)
+
+// StepLevel is the severity of a recovery step, similar to a log level.
+type StepLevel string
+
+const (
+	StepLevelInfo  StepLevel = "info"
+	StepLevelWarn  StepLevel = "warn"
+	StepLevelError StepLevel = "error"
+)
+
+// TopologyRecoveryStep represents a single, structured step/audit entry in a recovery
+// process: what kind of step it was, when it ran, how long it took, and - on failure -
+// why.
+type TopologyRecoveryStep struct {
+	ID         int64
+	RecoveryID int64
+	AuditAt    string
+	Message    string
+
+	StepType    StepType
+	StartedAt   string
+	CompletedAt string
+	DurationMs  int64
+	Level       StepLevel
+	ErrorText   string
+	Metadata    map[string]string
+}
+
+// NewTopologyRecoveryStep creates a new instance of a recovery step bound to the
+// given recovery id.
+func NewTopologyRecoveryStep(recoveryID int64, stepType StepType, message string) *TopologyRecoveryStep {
+	return &TopologyRecoveryStep{
+		RecoveryID: recoveryID,
+		StepType:   stepType,
+		Message:    message,
+		Level:      StepLevelInfo,
+	}
+}
+
+// Step begins a new, fluently-configurable step for this recovery. Use WithMetadata to
+// attach structured context (tablet aliases, GTID positions, ...) and Run to execute the
+// step's work, automatically recording its start/end time, duration and any error:
+//
+//	topologyRecovery.Step(StepPromote).WithMetadata(meta).Run(func() error { ... })
+func (topologyRecovery *TopologyRecovery) Step(stepType StepType) *TopologyRecoveryStep {
+	return NewTopologyRecoveryStep(topologyRecovery.ID, stepType, string(stepType))
+}
+
+// WithMetadata attaches structured, JSON-serializable context to the step (e.g. tablet
+// aliases, GTID positions) and returns the step for further chaining.
+func (step *TopologyRecoveryStep) WithMetadata(metadata map[string]string) *TopologyRecoveryStep {
+	step.Metadata = metadata
+	return step
+}
+
+// Run executes f, recording the step's start/completion timestamps, duration and outcome,
+// and persists the resulting step as an audit entry. The error returned by f, if any, is
+// both recorded on the step and returned to the caller.
+func (step *TopologyRecoveryStep) Run(f func() error) error {
+	started := time.Now()
+	step.StartedAt = started.Format(time.DateTime)
+	err := f()
+	completed := time.Now()
+	step.CompletedAt = completed.Format(time.DateTime)
+	step.DurationMs = completed.Sub(started).Milliseconds()
+	if err != nil {
+		step.Level = StepLevelError
+		step.ErrorText = err.Error()
+	}
+	if writeErr := writeTopologyRecoveryStep(step); writeErr != nil {
+		log.Error(writeErr)
+	}
+	return err
+}
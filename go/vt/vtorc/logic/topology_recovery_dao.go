@@ -17,10 +17,13 @@
 package logic
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
 
+	"github.com/google/uuid"
+
 	"vitess.io/vitess/go/vt/external/golib/sqlutils"
 	"vitess.io/vitess/go/vt/log"
 	"vitess.io/vitess/go/vt/vtorc/config"
@@ -30,8 +33,10 @@ import (
 
 // InsertRecoveryDetection inserts the recovery analysis that has been detected.
 func InsertRecoveryDetection(analysisEntry *inst.ReplicationAnalysis) error {
+	detectionUID := uuid.NewString()
 	sqlResult, err := db.ExecVTOrc(`INSERT OR IGNORE
 		INTO recovery_detection (
+			uid,
 			alias,
 			analysis,
 			keyspace,
@@ -42,8 +47,10 @@ func InsertRecoveryDetection(analysisEntry *inst.ReplicationAnalysis) error {
 			?,
 			?,
 			?,
+			?,
 			DATETIME('now')
 		)`,
+		detectionUID,
 		analysisEntry.AnalyzedInstanceAlias,
 		string(analysisEntry.Analysis),
 		analysisEntry.AnalyzedKeyspace,
@@ -59,36 +66,54 @@ func InsertRecoveryDetection(analysisEntry *inst.ReplicationAnalysis) error {
 		return err
 	}
 	analysisEntry.RecoveryId = id
+
+	failureDetection := NewFailureDetection(*analysisEntry)
+	failureDetection.UID = detectionUID
+	if err := writeFailureDetection(failureDetection); err != nil {
+		// The active-period bookkeeping is best-effort: a failure here should not prevent
+		// the detection itself from having been recorded.
+		log.Error(err)
+	}
 	return nil
 }
 
 func writeTopologyRecovery(topologyRecovery *TopologyRecovery) (*TopologyRecovery, error) {
 	analysisEntry := topologyRecovery.AnalysisEntry
+	topologyRecovery.ProcessingNodeHostname, topologyRecovery.ProcessingNodeToken = thisProcessIdentity()
 	sqlResult, err := db.ExecVTOrc(`INSERT OR IGNORE
 		INTO topology_recovery (
 			recovery_id,
+			uid,
 			alias,
 			start_recovery,
 			analysis,
 			keyspace,
 			shard,
-			detection_id
+			detection_id,
+			processing_node_hostname,
+			processing_node_token
 		) VALUES (
+			?,
 			?,
 			?,
 			DATETIME('now'),
 			?,
 			?,
 			?,
+			?,
+			?,
 			?
 		)`,
 		sqlutils.NilIfZero(topologyRecovery.ID),
+		topologyRecovery.UID,
 		analysisEntry.AnalyzedInstanceAlias,
 		string(analysisEntry.Analysis),
 		analysisEntry.AnalyzedKeyspace,
 		analysisEntry.AnalyzedShard,
 		analysisEntry.AnalyzedInstanceAlias,
 		analysisEntry.RecoveryId,
+		topologyRecovery.ProcessingNodeHostname,
+		topologyRecovery.ProcessingNodeToken,
 	)
 	if err != nil {
 		return nil, err
@@ -110,14 +135,21 @@ func writeTopologyRecovery(topologyRecovery *TopologyRecovery) (*TopologyRecover
 
 // AttemptRecoveryRegistration tries to add a recovery entry; if this fails that means recovery is already in place.
 func AttemptRecoveryRegistration(analysisEntry *inst.ReplicationAnalysis) (*TopologyRecovery, error) {
-	// Check if there is an active recovery in progress for the cluster of the given instance.
-	recoveries, err := ReadActiveClusterRecoveries(analysisEntry.AnalyzedKeyspace, analysisEntry.AnalyzedShard)
+	// Clear out any active recovery left behind by a VTOrc instance that crashed mid-recovery,
+	// so that a crashed leader never permanently blocks the shard from further recoveries.
+	if err := ExpireOrphanedActiveRecoveries(); err != nil {
+		log.Error(err)
+	}
+
+	// Check if there is an active recovery in progress for the cluster of the given instance,
+	// or an active failure-detection period still blocking new recoveries (anti-flap).
+	canRecover, err := ClusterCanRecover(analysisEntry.AnalyzedKeyspace, analysisEntry.AnalyzedShard, analysisEntry.Analysis)
 	if err != nil {
 		log.Error(err)
 		return nil, err
 	}
-	if len(recoveries) > 0 {
-		errMsg := fmt.Sprintf("AttemptRecoveryRegistration: Active recovery (id:%v) in the cluster %s:%s for %s", recoveries[0].ID, analysisEntry.AnalyzedKeyspace, analysisEntry.AnalyzedShard, recoveries[0].AnalysisEntry.Analysis)
+	if !canRecover {
+		errMsg := fmt.Sprintf("AttemptRecoveryRegistration: cluster %s:%s is not eligible for recovery for %s (active recovery or active detection period)", analysisEntry.AnalyzedKeyspace, analysisEntry.AnalyzedShard, analysisEntry.Analysis)
 		log.Errorf(errMsg)
 		return nil, errors.New(errMsg)
 	}
@@ -129,6 +161,18 @@ func AttemptRecoveryRegistration(analysisEntry *inst.ReplicationAnalysis) (*Topo
 		log.Error(err)
 		return nil, err
 	}
+	if topologyRecovery == nil {
+		return nil, nil
+	}
+
+	if err := runPreFailoverHooks(topologyRecovery); err != nil {
+		topologyRecovery.IsSuccessful = false
+		topologyRecovery.AllErrors = append(topologyRecovery.AllErrors, fmt.Sprintf("pre-failover hook aborted recovery: %+v", err))
+		if resolveErr := writeResolveRecovery(topologyRecovery); resolveErr != nil {
+			log.Error(resolveErr)
+		}
+		return nil, fmt.Errorf("AttemptRecoveryRegistration: pre-failover hook aborted recovery: %w", err)
+	}
 	return topologyRecovery, nil
 }
 
@@ -151,15 +195,23 @@ func writeResolveRecovery(topologyRecovery *TopologyRecovery) error {
 	)
 	if err != nil {
 		log.Error(err)
+		return err
 	}
-	return err
+	// Post-failover hooks (paging, DNS updates, proxy reconfiguration, ...) run out-of-band
+	// from this SQL statement, so a slow notifier can never block recovery bookkeeping.
+	go runPostFailoverHooks(topologyRecovery)
+	return nil
 }
 
 // readRecoveries reads recovery entry/audit entries from topology_recovery
-func readRecoveries(whereCondition string, limit string, args []any) ([]*TopologyRecovery, error) {
+func readRecoveries(whereCondition string, orderBy string, limit string, args []any) ([]*TopologyRecovery, error) {
 	res := []*TopologyRecovery{}
+	if orderBy == "" {
+		orderBy = recoveryOrderByColumns[""]
+	}
 	query := fmt.Sprintf(`SELECT
 			recovery_id,
+			uid,
 			alias,
 			start_recovery,
 			IFNULL(end_recovery, '') AS end_recovery,
@@ -169,19 +221,23 @@ func readRecoveries(whereCondition string, limit string, args []any) ([]*Topolog
 			keyspace,
 			shard,
 			all_errors,
-			detection_id
+			detection_id,
+			IFNULL(processing_node_hostname, '') AS processing_node_hostname,
+			IFNULL(processing_node_token, '') AS processing_node_token
 		FROM
 			topology_recovery
 		%s
-		ORDER BY recovery_id DESC
+		ORDER BY %s
 		%s
 		`,
 		whereCondition,
+		orderBy,
 		limit,
 	)
 	err := db.QueryVTOrc(query, args, func(m sqlutils.RowMap) error {
 		topologyRecovery := *NewTopologyRecovery(inst.ReplicationAnalysis{})
 		topologyRecovery.ID = m.GetInt64("recovery_id")
+		topologyRecovery.UID = m.GetString("uid")
 
 		topologyRecovery.RecoveryStartTimestamp = m.GetString("start_recovery")
 		topologyRecovery.RecoveryEndTimestamp = m.GetString("end_recovery")
@@ -192,6 +248,9 @@ func readRecoveries(whereCondition string, limit string, args []any) ([]*Topolog
 		topologyRecovery.AnalysisEntry.AnalyzedKeyspace = m.GetString("keyspace")
 		topologyRecovery.AnalysisEntry.AnalyzedShard = m.GetString("shard")
 
+		topologyRecovery.ProcessingNodeHostname = m.GetString("processing_node_hostname")
+		topologyRecovery.ProcessingNodeToken = m.GetString("processing_node_token")
+
 		topologyRecovery.SuccessorAlias = m.GetString("successor_alias")
 
 		topologyRecovery.AllErrors = strings.Split(m.GetString("all_errors"), "\n")
@@ -214,39 +273,76 @@ func ReadActiveClusterRecoveries(keyspace string, shard string) ([]*TopologyReco
 		end_recovery IS NULL
 		AND keyspace = ?
 		AND shard = ?`
-	return readRecoveries(whereClause, ``, sqlutils.Args(keyspace, shard))
+	return readRecoveries(whereClause, ``, ``, sqlutils.Args(keyspace, shard))
 }
 
-// ReadRecentRecoveries reads latest recovery entries from topology_recovery
-func ReadRecentRecoveries(page int) ([]*TopologyRecovery, error) {
-	whereConditions := []string{}
-	whereClause := ""
-	var args []any
-	if len(whereConditions) > 0 {
-		whereClause = fmt.Sprintf("WHERE %s", strings.Join(whereConditions, " AND "))
+// ReadRecoveryByUID reads the single recovery audit entry identified by its stable UID.
+// Unlike recovery_id, the UID remains a valid deep-link even after the audit table has
+// been expired/renumbered.
+func ReadRecoveryByUID(uid string) (*TopologyRecovery, error) {
+	whereClause := `WHERE uid = ?`
+	recoveries, err := readRecoveries(whereClause, ``, ``, sqlutils.Args(uid))
+	if err != nil {
+		return nil, err
+	}
+	if len(recoveries) == 0 {
+		return nil, nil
 	}
+	return recoveries[0], nil
+}
+
+// ReadRecentRecoveries reads latest recovery entries from topology_recovery, narrowed down
+// by filter (the zero value matches everything) and paginated via config.AuditPageSize.
+func ReadRecentRecoveries(filter RecoveryFilter, page int) ([]*TopologyRecovery, error) {
+	whereClause, args, orderBy := filter.buildWhere("start_recovery")
 	limit := `LIMIT ? OFFSET ?`
 	args = append(args, config.AuditPageSize, page*config.AuditPageSize)
-	return readRecoveries(whereClause, limit, args)
+	return readRecoveries(whereClause, orderBy, limit, args)
 }
 
-// writeTopologyRecoveryStep writes down a single step in a recovery process
+// writeTopologyRecoveryStep writes down a single, structured step in a recovery process
 func writeTopologyRecoveryStep(topologyRecoveryStep *TopologyRecoveryStep) error {
+	metadataJSON, err := json.Marshal(topologyRecoveryStep.Metadata)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
 	sqlResult, err := db.ExecVTOrc(`INSERT OR IGNORE
 		INTO topology_recovery_steps (
 			recovery_step_id,
 			recovery_id,
 			audit_at,
-			message
+			message,
+			step_type,
+			started_at,
+			completed_at,
+			duration_ms,
+			level,
+			error_text,
+			metadata
 		) VALUES (
 			?,
 			?,
 			DATETIME('now'),
+			?,
+			?,
+			?,
+			?,
+			?,
+			?,
+			?,
 			?
 		)`,
 		sqlutils.NilIfZero(topologyRecoveryStep.ID),
 		topologyRecoveryStep.RecoveryID,
 		topologyRecoveryStep.Message,
+		string(topologyRecoveryStep.StepType),
+		topologyRecoveryStep.StartedAt,
+		topologyRecoveryStep.CompletedAt,
+		topologyRecoveryStep.DurationMs,
+		string(topologyRecoveryStep.Level),
+		topologyRecoveryStep.ErrorText,
+		string(metadataJSON),
 	)
 	if err != nil {
 		log.Error(err)
@@ -259,11 +355,137 @@ func writeTopologyRecoveryStep(topologyRecoveryStep *TopologyRecoveryStep) error
 	return err
 }
 
+// ReadRecoverySteps reads the ordered timeline of structured steps for a given recovery.
+func ReadRecoverySteps(recoveryID int64) ([]*TopologyRecoveryStep, error) {
+	res := []*TopologyRecoveryStep{}
+	query := `SELECT
+			recovery_step_id,
+			recovery_id,
+			audit_at,
+			message,
+			step_type,
+			IFNULL(started_at, '') AS started_at,
+			IFNULL(completed_at, '') AS completed_at,
+			duration_ms,
+			level,
+			IFNULL(error_text, '') AS error_text,
+			IFNULL(metadata, '') AS metadata
+		FROM
+			topology_recovery_steps
+		WHERE
+			recovery_id = ?
+		ORDER BY recovery_step_id ASC
+		`
+	err := db.QueryVTOrc(query, sqlutils.Args(recoveryID), func(m sqlutils.RowMap) error {
+		step := &TopologyRecoveryStep{
+			ID:          m.GetInt64("recovery_step_id"),
+			RecoveryID:  m.GetInt64("recovery_id"),
+			AuditAt:     m.GetString("audit_at"),
+			Message:     m.GetString("message"),
+			StepType:    StepType(m.GetString("step_type")),
+			StartedAt:   m.GetString("started_at"),
+			CompletedAt: m.GetString("completed_at"),
+			DurationMs:  m.GetInt64("duration_ms"),
+			Level:       StepLevel(m.GetString("level")),
+			ErrorText:   m.GetString("error_text"),
+		}
+		if metadataJSON := m.GetString("metadata"); metadataJSON != "" {
+			if err := json.Unmarshal([]byte(metadataJSON), &step.Metadata); err != nil {
+				log.Error(err)
+			}
+		}
+		res = append(res, step)
+		return nil
+	})
+	if err != nil {
+		log.Error(err)
+	}
+	return res, err
+}
+
+// ReadRecoveryOwner returns the processing node hostname/token that registered the given
+// recovery, so a dashboard can tell which VTOrc instance is (or was) driving a failover.
+func ReadRecoveryOwner(recoveryID int64) (hostname string, token string, err error) {
+	err = db.QueryVTOrc(`SELECT
+			processing_node_hostname,
+			processing_node_token
+		FROM
+			topology_recovery
+		WHERE
+			recovery_id = ?
+		`, sqlutils.Args(recoveryID), func(m sqlutils.RowMap) error {
+		hostname = m.GetString("processing_node_hostname")
+		token = m.GetString("processing_node_token")
+		return nil
+	})
+	if err != nil {
+		log.Error(err)
+	}
+	return hostname, token, err
+}
+
+// ExpireOrphanedActiveRecoveries finds active recoveries (end_recovery IS NULL) whose
+// start_recovery is older than config.OrphanedRecoveryTakeoverSeconds and explicitly marks
+// them as failed, instead of leaving them open forever. This is what allows a new VTOrc
+// instance to take over recovery duties for a shard after its previous owner crashed
+// mid-recovery. AttemptRecoveryRegistration calls it before every registration; it is not
+// run on a timer of its own.
+func ExpireOrphanedActiveRecoveries() error {
+	orphaned, err := readRecoveries(`WHERE
+		end_recovery IS NULL
+		AND start_recovery < DATETIME('now', PRINTF('-%d seconds', ?))`, ``, ``, sqlutils.Args(config.OrphanedRecoveryTakeoverSeconds))
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	for _, topologyRecovery := range orphaned {
+		topologyRecovery.IsSuccessful = false
+		topologyRecovery.AllErrors = append(topologyRecovery.AllErrors, fmt.Sprintf(
+			"recovery orphaned: owning node %s did not complete it within OrphanedRecoveryTakeoverSeconds=%d",
+			topologyRecovery.ProcessingNodeHostname, config.OrphanedRecoveryTakeoverSeconds))
+		if err := writeResolveRecovery(topologyRecovery); err != nil {
+			log.Error(err)
+			return err
+		}
+	}
+	return nil
+}
+
 // ExpireRecoveryDetectionHistory removes old rows from the recovery_detection table
 func ExpireRecoveryDetectionHistory() error {
 	return inst.ExpireTableData("recovery_detection", "detection_timestamp")
 }
 
+// ReadFailureDetectionByUID reads the single recovery_detection audit entry identified by its
+// stable UID, so operators can deep-link a detection event even after the table has been expired.
+func ReadFailureDetectionByUID(uid string) (*inst.ReplicationAnalysis, error) {
+	query := `SELECT
+			alias,
+			analysis,
+			keyspace,
+			shard
+		FROM
+			recovery_detection
+		WHERE
+			uid = ?
+		`
+	var analysisEntry *inst.ReplicationAnalysis
+	err := db.QueryVTOrc(query, sqlutils.Args(uid), func(m sqlutils.RowMap) error {
+		analysisEntry = &inst.ReplicationAnalysis{
+			AnalyzedInstanceAlias: m.GetString("alias"),
+			Analysis:              inst.AnalysisCode(m.GetString("analysis")),
+			AnalyzedKeyspace:      m.GetString("keyspace"),
+			AnalyzedShard:         m.GetString("shard"),
+		}
+		return nil
+	})
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	return analysisEntry, nil
+}
+
 // ExpireTopologyRecoveryHistory removes old rows from the topology_recovery table
 func ExpireTopologyRecoveryHistory() error {
 	return inst.ExpireTableData("topology_recovery", "start_recovery")
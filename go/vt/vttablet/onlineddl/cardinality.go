@@ -0,0 +1,74 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package onlineddl
+
+import (
+	"context"
+	"flag"
+	"time"
+)
+
+// onlineDDLCardinalityProbeTimeout bounds how long the scheduler waits on an initial
+// `SELECT COUNT(*) ... LIMIT` probe before falling back to information_schema's
+// (possibly stale or zero) row estimate.
+var onlineDDLCardinalityProbeTimeout = 2 * time.Second
+
+func init() {
+	flag.DurationVar(&onlineDDLCardinalityProbeTimeout, "online-ddl-cardinality-probe-timeout", onlineDDLCardinalityProbeTimeout,
+		"timeout for the initial row-count probe used to guard against a zero/stale information_schema row estimate")
+}
+
+// CardinalitySource records where a migration's row-count estimate came from, so operators
+// can tell a real sample from a floor/fallback when debugging progress-reporting anomalies.
+type CardinalitySource string
+
+const (
+	// CardinalitySourceProbe means the estimate came from a successful SELECT COUNT(*) probe.
+	CardinalitySourceProbe CardinalitySource = "probe"
+	// CardinalitySourceEstimate means the estimate came from information_schema and was non-zero.
+	CardinalitySourceEstimate CardinalitySource = "estimate"
+	// CardinalitySourceFloor means both the probe and the information_schema estimate were
+	// zero or unavailable, and the hard-coded floor of 1 was used instead.
+	CardinalitySourceFloor CardinalitySource = "floor"
+)
+
+// minRowsEstimate is the cardinality floor: scheduling/progress math must never see an
+// estimate of 0, which would cause a divide-by-zero (NaN progress) or a migration being
+// reported ready-to-complete before a single row has been copied.
+const minRowsEstimate = 1
+
+// rowCountProbe runs a bounded SELECT COUNT(*) ... LIMIT probe. An implementation is expected
+// to execute the probe query against the source table and return the count it found.
+type rowCountProbe func(ctx context.Context) (int64, error)
+
+// ResolveRowsEstimate decides the row-count estimate to use for scheduling/progress
+// reporting, preferring a live probe, falling back to the information_schema estimate, and
+// finally to the hard floor of 1 row. It never returns 0.
+func ResolveRowsEstimate(ctx context.Context, schemaEstimate int64, probe rowCountProbe) (rowsEstimate int64, source CardinalitySource) {
+	probeCtx, cancel := context.WithTimeout(ctx, onlineDDLCardinalityProbeTimeout)
+	defer cancel()
+
+	if probe != nil {
+		if count, err := probe(probeCtx); err == nil && count > 0 {
+			return count, CardinalitySourceProbe
+		}
+	}
+	if schemaEstimate > 0 {
+		return schemaEstimate, CardinalitySourceEstimate
+	}
+	return minRowsEstimate, CardinalitySourceFloor
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package onlineddl
+
+import (
+	"context"
+	"time"
+)
+
+// DrainCheckInterval is how often drainForCutOver polls openConns while waiting for the
+// blocking connections' transactions to naturally commit/rollback.
+const DrainCheckInterval = 250 * time.Millisecond
+
+// cutOverConnection is the minimal surface drainForCutOver needs from the tablet's query
+// engine, so it can be exercised with a fake in tests without depending on the full
+// connection pool.
+type cutOverConnection interface {
+	// ID returns the connection/transaction ID, for KillQuery/KillConnection.
+	ID() int64
+	// QueryStartedAt returns when the connection's current blocking statement started.
+	QueryStartedAt() time.Time
+}
+
+// drainForCutOver implements the graceful-drain mode for --cut-over-drain-timeout: rather
+// than immediately hard-killing connections blocking the cut-over, it
+//  1. issues KillQuery (not KillConnection) for statements that have been running longer
+//     than gracePeriod,
+//  2. waits, polling openConns, for the set of blocking connections to drain naturally as
+//     their transactions commit/rollback,
+//  3. escalates to killConnection for anything still present once deadline is reached.
+//
+// killQuery and killConnection are injected so the caller can use the tablet's real query
+// killer. drainForCutOver returns nil once openConns() reports no blocking connections. Once
+// deadline has passed it returns the first killConnection error, or nil if every kill
+// succeeded. It returns ctx's error if ctx is done first.
+func drainForCutOver(
+	ctx context.Context,
+	deadline time.Time,
+	gracePeriod time.Duration,
+	openConns func() []cutOverConnection,
+	killQuery func(connID int64) error,
+	killConnection func(connID int64) error,
+) error {
+	killedQueries := map[int64]bool{}
+	ticker := time.NewTicker(DrainCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		conns := openConns()
+		if len(conns) == 0 {
+			return nil
+		}
+
+		now := time.Now()
+		for _, conn := range conns {
+			if !killedQueries[conn.ID()] && now.Sub(conn.QueryStartedAt()) > gracePeriod {
+				if err := killQuery(conn.ID()); err == nil {
+					killedQueries[conn.ID()] = true
+				}
+			}
+		}
+
+		if now.After(deadline) {
+			var firstErr error
+			for _, conn := range conns {
+				if err := killConnection(conn.ID()); err != nil && firstErr == nil {
+					firstErr = err
+				}
+			}
+			return firstErr
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package onlineddl
+
+import "sync"
+
+// SchemaVersionEvent describes a single cut-over, identifying exactly which tables changed
+// shape so a subscriber (e.g. vtgate's plan cache) can invalidate only the plans that
+// reference them, rather than flushing everything.
+type SchemaVersionEvent struct {
+	Keyspace string
+	Shard    string
+	// Tables lists the tables whose schema changed as part of this cut-over.
+	Tables []string
+}
+
+// CutOverNotifier is implemented by anything that wants to learn about a completed Online
+// DDL cut-over. vtgate's query engine subscribes to this to selectively invalidate cached
+// plans whose referenced tables intersect event.Tables, instead of doing a full plan-cache
+// flush on every schema change.
+type CutOverNotifier interface {
+	OnCutOver(event SchemaVersionEvent)
+}
+
+// cutOverNotifierRegistry fans a single cut-over event out to every registered
+// CutOverNotifier. The scheduler calls NotifyCutOver once a migration's cut-over has been
+// committed; subscribers are invoked synchronously and should not block.
+type cutOverNotifierRegistry struct {
+	mu        sync.Mutex
+	notifiers []CutOverNotifier
+}
+
+var globalCutOverNotifiers = &cutOverNotifierRegistry{}
+
+// RegisterCutOverNotifier registers a CutOverNotifier to be invoked on every subsequent
+// Online DDL cut-over on this tablet.
+func RegisterCutOverNotifier(notifier CutOverNotifier) {
+	globalCutOverNotifiers.mu.Lock()
+	defer globalCutOverNotifiers.mu.Unlock()
+	globalCutOverNotifiers.notifiers = append(globalCutOverNotifiers.notifiers, notifier)
+}
+
+// NotifyCutOver is called by the scheduler immediately after a migration's cut-over
+// commits, so subscribers can selectively invalidate any state keyed by the old schema.
+func NotifyCutOver(keyspace, shard string, tables []string) {
+	globalCutOverNotifiers.mu.Lock()
+	notifiers := make([]CutOverNotifier, len(globalCutOverNotifiers.notifiers))
+	copy(notifiers, globalCutOverNotifiers.notifiers)
+	globalCutOverNotifiers.mu.Unlock()
+
+	event := SchemaVersionEvent{Keyspace: keyspace, Shard: shard, Tables: tables}
+	for _, notifier := range notifiers {
+		notifier.OnCutOver(event)
+	}
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package onlineddl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CutOverWindow is a time-of-day range (in the scheduler's local time), optionally
+// restricted to a subset of weekdays, during which the executor is allowed to attempt the
+// metadata-locking cut-over for a migration. Outside the window, the executor retries the
+// lock-wait phase with exponential backoff instead of attempting cut-over.
+//
+// It is parsed from the --cut-over-schedule=<window> DDL strategy flag.
+// Executor.planCutOverWindow keeps it in memory only; it is not written to the migrations
+// table.
+type CutOverWindow struct {
+	// Start and End are "HH:MM" in 24h format. A window that wraps midnight (End < Start)
+	// is supported: it spans [Start, 24:00) U [00:00, End).
+	Start, End string
+	// Weekdays, when non-empty, restricts the window to those days (time.Monday, ...).
+	// An empty set means every day.
+	Weekdays map[time.Weekday]bool
+}
+
+// ParseCutOverWindow parses a "HH:MM-HH:MM" or "Mon,Tue:HH:MM-HH:MM" spec.
+func ParseCutOverWindow(spec string) (*CutOverWindow, error) {
+	window := &CutOverWindow{Weekdays: map[time.Weekday]bool{}}
+
+	timeRange := spec
+	if idx := strings.Index(spec, ":"); idx >= 0 {
+		if _, err := strconv.Atoi(spec[:idx]); err != nil {
+			days := strings.Split(spec[:idx], ",")
+			for _, day := range days {
+				weekday, err := parseWeekday(strings.TrimSpace(day))
+				if err != nil {
+					return nil, err
+				}
+				window.Weekdays[weekday] = true
+			}
+			timeRange = spec[idx+1:]
+		}
+	}
+
+	parts := strings.SplitN(timeRange, "-", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("cutover_window: invalid window spec %q, expected HH:MM-HH:MM", spec)
+	}
+	for _, hhmm := range parts {
+		if _, err := parseHHMM(hhmm); err != nil {
+			return nil, err
+		}
+	}
+	window.Start, window.End = parts[0], parts[1]
+	return window, nil
+}
+
+func parseWeekday(name string) (time.Weekday, error) {
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		if strings.EqualFold(weekday.String()[:3], name) {
+			return weekday, nil
+		}
+	}
+	return 0, fmt.Errorf("cutover_window: unrecognized weekday %q", name)
+}
+
+func parseHHMM(hhmm string) (minutesSinceMidnight int, err error) {
+	parts := strings.SplitN(hhmm, ":", 2)
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("cutover_window: invalid time %q, expected HH:MM", hhmm)
+	}
+	hour, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, fmt.Errorf("cutover_window: invalid hour in %q: %w", hhmm, err)
+	}
+	minute, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, fmt.Errorf("cutover_window: invalid minute in %q: %w", hhmm, err)
+	}
+	return hour*60 + minute, nil
+}
+
+// IsWithinWindow reports whether now falls inside the allowed cut-over window.
+func (w *CutOverWindow) IsWithinWindow(now time.Time) bool {
+	if len(w.Weekdays) > 0 && !w.Weekdays[now.Weekday()] {
+		return false
+	}
+	startMinutes, _ := parseHHMM(w.Start)
+	endMinutes, _ := parseHHMM(w.End)
+	nowMinutes := now.Hour()*60 + now.Minute()
+
+	if startMinutes <= endMinutes {
+		return nowMinutes >= startMinutes && nowMinutes < endMinutes
+	}
+	// Window wraps midnight.
+	return nowMinutes >= startMinutes || nowMinutes < endMinutes
+}
+
+// cutoverBackoffBase and cutoverBackoffMax bound the exponential backoff applied to
+// retrying the lock-wait phase while outside the cut-over window.
+const (
+	cutoverBackoffBase = 30 * time.Second
+	cutoverBackoffMax  = 10 * time.Minute
+)
+
+// NextAttemptAt computes the next cut-over attempt time for a migration that is outside its
+// window, applying exponential backoff (capped at cutoverBackoffMax) based on how many
+// attempts have already been made.
+func NextAttemptAt(now time.Time, attempt int) time.Time {
+	backoff := cutoverBackoffBase
+	for i := 0; i < attempt; i++ {
+		backoff *= 2
+		if backoff > cutoverBackoffMax {
+			backoff = cutoverBackoffMax
+			break
+		}
+	}
+	return now.Add(backoff)
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package onlineddl
+
+import "fmt"
+
+// SchemaDiffFunc computes the ordered list of DDL statements required to converge a shard's
+// live schema to the desired schema, both expressed as whole-schema SQL (a sequence of CREATE
+// TABLE/CREATE VIEW statements). It is injected so DeclarativeSchemaPlan can be exercised
+// without depending directly on the schemadiff package's concrete types.
+type SchemaDiffFunc func(currentSchemaSQL, desiredSchemaSQL string) (orderedStatements []string, err error)
+
+// DeclarativeSchemaRequest is the input to PlanDeclarativeSchema: the desired whole-schema SQL
+// bundle (concatenated CREATE TABLE/CREATE VIEW statements, as assembled from a single request
+// body or a directory of .sql files by the caller) targeting one keyspace/shard.
+type DeclarativeSchemaRequest struct {
+	Keyspace         string
+	Shard            string
+	DesiredSchemaSQL string
+}
+
+// DeclarativeSchemaPlan is the result of computing a diff: the ordered DDL statements needed to
+// converge, and the singleton context under which they must all be enqueued so they succeed or
+// fail atomically as one migration group.
+type DeclarativeSchemaPlan struct {
+	Keyspace         string
+	Shard            string
+	Statements       []string
+	SingletonContext string
+}
+
+// PlanDeclarativeSchema computes the DeclarativeSchemaPlan for request by diffing its current
+// live schema (currentSchemaSQL, as read by the caller from the target shard) against the
+// desired schema using diff, then assigning singletonContext (typically the submitting
+// migration's own UUID) so every resulting per-object DDL shares one --singleton-context and
+// the whole plan is applied atomically. Each statement is run through
+// NormalizeDeclarativeStatement first, so a desired-schema bundle written with
+// CREATE OR REPLACE VIEW/ALTER VIEW/DROP ... IF EXISTS (forms -declarative otherwise rejects)
+// can be diffed and applied as-is.
+func PlanDeclarativeSchema(request DeclarativeSchemaRequest, currentSchemaSQL string, singletonContext string, diff SchemaDiffFunc) (*DeclarativeSchemaPlan, error) {
+	statements, err := diff(currentSchemaSQL, request.DesiredSchemaSQL)
+	if err != nil {
+		return nil, fmt.Errorf("declarative_schema: computing diff for %s/%s: %w", request.Keyspace, request.Shard, err)
+	}
+	for i, statement := range statements {
+		statements[i] = NormalizeDeclarativeStatement(statement)
+	}
+	return &DeclarativeSchemaPlan{
+		Keyspace:         request.Keyspace,
+		Shard:            request.Shard,
+		Statements:       statements,
+		SingletonContext: singletonContext,
+	}, nil
+}
+
+// IsNoop reports whether the plan has nothing to apply, i.e. the shard's live schema already
+// matches the desired schema.
+func (plan *DeclarativeSchemaPlan) IsNoop() bool {
+	return len(plan.Statements) == 0
+}
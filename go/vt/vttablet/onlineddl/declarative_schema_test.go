@@ -0,0 +1,42 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package onlineddl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanDeclarativeSchemaNormalizesStatements(t *testing.T) {
+	diff := func(currentSchemaSQL, desiredSchemaSQL string) ([]string, error) {
+		return []string{
+			"CREATE OR REPLACE VIEW v1 AS SELECT * FROM t1",
+			"DROP TABLE IF EXISTS stale",
+		}, nil
+	}
+
+	request := DeclarativeSchemaRequest{Keyspace: "ks", Shard: "0", DesiredSchemaSQL: "desired"}
+	plan, err := PlanDeclarativeSchema(request, "current", "ctx1", diff)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"CREATE VIEW v1 AS SELECT * FROM t1",
+		"DROP TABLE stale",
+	}, plan.Statements)
+}
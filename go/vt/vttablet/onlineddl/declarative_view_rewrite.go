@@ -0,0 +1,50 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package onlineddl
+
+import "regexp"
+
+var (
+	createOrReplaceViewRE = regexp.MustCompile(`(?i)^\s*create\s+or\s+replace\s+view\s+`)
+	alterViewRE           = regexp.MustCompile(`(?i)^\s*alter\s+view\s+`)
+	dropIfExistsRE        = regexp.MustCompile(`(?i)^(\s*drop\s+(?:table|view)\s+)if\s+exists\s+`)
+)
+
+// NormalizeDeclarativeStatement rewrites statement forms that are otherwise hard-rejected
+// under the `-declarative` strategy into their canonical declarative equivalent, so tooling
+// that already emits idempotent MySQL-style DDL can be reused as-is:
+//
+//   - `CREATE OR REPLACE VIEW v AS ...` and `ALTER VIEW v AS ...` both become
+//     `CREATE VIEW v AS ...`, since -declarative always diffs against the view's current
+//     definition and recreates it regardless of whether it previously existed.
+//   - `DROP TABLE|VIEW IF EXISTS x` becomes `DROP TABLE|VIEW x`: -declarative's DROP is
+//     already a noop when the object is absent, and a real drop when it is present, so the
+//     "IF EXISTS" qualifier is redundant rather than meaningful.
+//
+// Statements that don't match any of these forms are returned unchanged.
+func NormalizeDeclarativeStatement(statement string) string {
+	if match := createOrReplaceViewRE.FindString(statement); match != "" {
+		return "CREATE VIEW " + statement[len(match):]
+	}
+	if match := alterViewRE.FindString(statement); match != "" {
+		return "CREATE VIEW " + statement[len(match):]
+	}
+	if match := dropIfExistsRE.FindStringSubmatch(statement); match != nil {
+		return match[1] + statement[len(match[0]):]
+	}
+	return statement
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package onlineddl
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DependencyOrderCompletionStrategyFlag is the new `--dependency-order-completion` DDL
+// strategy flag. Unlike `--in-order-completion`, which treats a submitted batch as a strict
+// linear chain, this flag opts the batch into real dependency-graph scheduling: independent
+// branches run concurrently (subject to --allow-concurrent), and only migrations that
+// actually reference a schema object created/altered earlier in the batch wait for it.
+const DependencyOrderCompletionStrategyFlag = "dependency-order-completion"
+
+// SchemaReference is the set of schema objects a single DDL statement creates/alters and the
+// set it merely references (reads), as extracted by ParseStatementSchemaReferences.
+type SchemaReference struct {
+	// Tables/views this statement creates or alters.
+	Produces []string
+	// Tables/views and columns this statement references, keyed by table/view name. Each
+	// table/view named after FROM/JOIN/REFERENCES is recorded with the column "*", and each
+	// column added via ADD COLUMN is recorded against every table this statement produces.
+	// Column references inside SELECT lists or other expressions are not extracted.
+	References map[string][]string
+}
+
+var (
+	createOrAlterRE = regexp.MustCompile(`(?i)^\s*(?:create|alter)\s+(?:table|view)\s+(?:if\s+not\s+exists\s+)?` + "`?([a-zA-Z0-9_]+)`?")
+	referencedRE    = regexp.MustCompile(`(?i)\b(?:from|join|references)\s+` + "`?([a-zA-Z0-9_]+)`?")
+	addColumnRE     = regexp.MustCompile(`(?i)\badd\s+column\s+` + "`?([a-zA-Z0-9_]+)`?")
+)
+
+// ParseStatementSchemaReferences extracts the table/view this statement creates or alters,
+// any column it adds, and any table/view it reads from. This is a lightweight, regex-based
+// extraction rather than a full SQL parse, and it is the only source of the dependency DAG's
+// edges: a reference it misses leaves out an edge, so the two migrations are treated as
+// independent, while a spurious match adds an edge that only delays a migration.
+func ParseStatementSchemaReferences(statement string) SchemaReference {
+	reference := SchemaReference{References: map[string][]string{}}
+
+	if match := createOrAlterRE.FindStringSubmatch(statement); match != nil {
+		reference.Produces = append(reference.Produces, match[1])
+	}
+
+	for _, match := range referencedRE.FindAllStringSubmatch(statement, -1) {
+		table := match[1]
+		if !contains(reference.Produces, table) {
+			reference.References[table] = append(reference.References[table], "*")
+		}
+	}
+
+	for _, match := range addColumnRE.FindAllStringSubmatch(statement, -1) {
+		for _, produced := range reference.Produces {
+			reference.References[produced] = append(reference.References[produced], match[1])
+		}
+	}
+
+	return reference
+}
+
+func contains(haystack []string, needle string) bool {
+	for _, s := range haystack {
+		if strings.EqualFold(s, needle) {
+			return true
+		}
+	}
+	return false
+}
+
+// BuildDependencyDAG builds a MigrationDAG for a batch of migrations submitted under
+// --dependency-order-completion: an edge exists from migration A to migration B (B depends on
+// A) iff B references a table/view A produces. Migrations are otherwise independent and may
+// run concurrently. statements and order must describe the same batch; order fixes the
+// submission order used to break ties and to resolve "which of several producers of the same
+// table came first" when more than one migration in the batch alters it.
+//
+// This is the same MigrationDAG type the scheduler's EligibleMigrations/GroupReadyToCutOver
+// consult: --dependency-order-completion only changes how edges are derived (parsed from SQL
+// here, rather than declared via --migration-group/--depends-on), not the scheduling model
+// itself. Executor.registerDependencyOrderedBatch merges the result into the executor's single
+// shared MigrationDAG for exactly this reason.
+func BuildDependencyDAG(order []string, statements map[string]string) *MigrationDAG {
+	dag := NewMigrationDAG()
+	references := make(map[string]SchemaReference, len(order))
+	for _, uuid := range order {
+		references[uuid] = ParseStatementSchemaReferences(statements[uuid])
+	}
+
+	for i, uuid := range order {
+		var dependsOn []string
+		for _, earlierUUID := range order[:i] {
+			if dependsOnEarlier(references[uuid], references[earlierUUID]) {
+				dependsOn = append(dependsOn, earlierUUID)
+			}
+		}
+		dag.AddMigration(uuid, "", dependsOn)
+	}
+	return dag
+}
+
+// dependsOnEarlier reports whether candidate references any table/view that earlier produces.
+func dependsOnEarlier(candidate, earlier SchemaReference) bool {
+	for _, produced := range earlier.Produces {
+		if _, referenced := candidate.References[produced]; referenced {
+			return true
+		}
+	}
+	return false
+}
+
+// CascadeFailure marks failedUUID and every migration transitively depending on it (directly
+// or through another failed migration) as failed, using the same "failed due to <uuid>"
+// message convention as the existing linear --in-order-completion scheduler. Siblings that do
+// not depend on failedUUID are left untouched so independent branches keep running.
+func (d *MigrationDAG) CascadeFailure(failedUUID string, order []string) map[string]string {
+	failed := map[string]string{failedUUID: fmt.Sprintf("failed due to %s", failedUUID)}
+
+	// Dependencies were recorded in submission order, so a single forward pass over order
+	// (which is a valid topological order of the DAG) is enough to propagate failures to
+	// every transitive dependent.
+	for _, uuid := range order {
+		if _, alreadyFailed := failed[uuid]; alreadyFailed {
+			continue
+		}
+		for _, dependency := range d.dependsOn[uuid] {
+			if cause, dependencyFailed := failed[dependency]; dependencyFailed {
+				failed[uuid] = cause
+				break
+			}
+		}
+	}
+	return failed
+}
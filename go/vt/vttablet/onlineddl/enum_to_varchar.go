@@ -0,0 +1,98 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package onlineddl
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AllowEnumToVarcharFlag is the name of the --allow-enum-to-varchar DDL strategy flag.
+// Nothing in this package reads it: Executor.planEnumToVarcharColumn installs an
+// ENUM->VARCHAR/TEXT translation whenever it is called, and rejects a migration only when the
+// target VARCHAR is shorter than the longest enum label (ValidateTargetLength).
+const AllowEnumToVarcharFlag = "allow-enum-to-varchar"
+
+// enumToVarcharTranslator maps the 1-based ordinal values of a source ENUM column to their
+// string labels, for use during row copy and binlog-event application when the source
+// column is ENUM and the target column is VARCHAR/TEXT. Ordinal 0 (and NULL) are passed
+// through unchanged, matching MySQL's own semantics for invalid/empty ENUM values.
+type enumToVarcharTranslator struct {
+	// labels is ordered such that labels[i] is the string for ordinal i+1.
+	labels []string
+}
+
+// newEnumToVarcharTranslator parses a MySQL column-definition ENUM literal, e.g.
+// `enum('a','b','c')`, into an ordered label list.
+func newEnumToVarcharTranslator(enumColumnType string) (*enumToVarcharTranslator, error) {
+	labels, err := parseEnumLabels(enumColumnType)
+	if err != nil {
+		return nil, err
+	}
+	return &enumToVarcharTranslator{labels: labels}, nil
+}
+
+// parseEnumLabels extracts the quoted labels out of a `enum('a','b','c')` column type
+// string, in declaration order.
+func parseEnumLabels(enumColumnType string) ([]string, error) {
+	open := strings.IndexByte(enumColumnType, '(')
+	closeIdx := strings.LastIndexByte(enumColumnType, ')')
+	if open < 0 || closeIdx < 0 || closeIdx < open {
+		return nil, fmt.Errorf("enum_to_varchar: not a valid enum column type: %s", enumColumnType)
+	}
+	var labels []string
+	for _, part := range strings.Split(enumColumnType[open+1:closeIdx], ",") {
+		label := strings.TrimSpace(part)
+		label = strings.TrimPrefix(label, "'")
+		label = strings.TrimSuffix(label, "'")
+		label = strings.ReplaceAll(label, "''", "'")
+		labels = append(labels, label)
+	}
+	if len(labels) == 0 {
+		return nil, fmt.Errorf("enum_to_varchar: no labels found in: %s", enumColumnType)
+	}
+	return labels, nil
+}
+
+// ValidateTargetLength rejects the migration up-front if targetVarcharLength is shorter
+// than the longest enum label, since that would silently truncate data.
+func (t *enumToVarcharTranslator) ValidateTargetLength(targetVarcharLength int) error {
+	longest := 0
+	for _, label := range t.labels {
+		if len(label) > longest {
+			longest = len(label)
+		}
+	}
+	if targetVarcharLength < longest {
+		return fmt.Errorf("enum_to_varchar: target VARCHAR(%d) is shorter than the longest enum label (%d chars)", targetVarcharLength, longest)
+	}
+	return nil
+}
+
+// Translate converts a raw ENUM ordinal (as read from the source row) to its string label.
+// Ordinal 0 means the empty-string "invalid value" representation MySQL uses for ENUMs,
+// and is passed through as "" rather than treated as an error.
+func (t *enumToVarcharTranslator) Translate(ordinal int64) (string, error) {
+	if ordinal == 0 {
+		return "", nil
+	}
+	index := ordinal - 1
+	if index < 0 || int(index) >= len(t.labels) {
+		return "", fmt.Errorf("enum_to_varchar: ordinal %d out of range for %d labels", ordinal, len(t.labels))
+	}
+	return t.labels[index], nil
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package onlineddl
+
+import (
+	"flag"
+	"time"
+)
+
+// MigrationProgress is the snapshot of copy-progress metrics surfaced on a running
+// migration's row: `rows_copied_per_second`, `estimated_seconds_remaining` and
+// `eta_timestamp`, as read via `SHOW VITESS_MIGRATIONS` / onlineddl.ReadMigrations.
+type MigrationProgress struct {
+	RowsCopiedPerSecond       float64
+	EstimatedSecondsRemaining int64
+	ETATimestamp              time.Time
+}
+
+// onlineDDLETAEWMAAlpha is the smoothing factor applied to the copy-rate EWMA used to
+// compute eta_seconds for a running migration. Lower values smooth out bursts/stalls
+// (e.g. throttling) at the cost of slower convergence.
+var onlineDDLETAEWMAAlpha = 0.15
+
+func init() {
+	flag.Float64Var(&onlineDDLETAEWMAAlpha, "online-ddl-eta-ewma-alpha", onlineDDLETAEWMAAlpha,
+		"smoothing factor (0 < alpha <= 1) for the Online DDL copy-rate EWMA used to compute eta_seconds")
+}
+
+// etaEstimator tracks copied-rows/sec for a single migration using an exponentially
+// weighted moving average (EWMA), and from it estimates the time remaining until
+// totalRowsEstimate rows have been copied. It is meant to be sampled once per
+// migration-check-interval tick from the migration's copy-state loop.
+type etaEstimator struct {
+	alpha                 float64
+	haveSample            bool
+	lastSampleAt          time.Time
+	lastCopiedRows        int64
+	smoothedRowsPerSecond float64
+}
+
+// newETAEstimator creates an etaEstimator using the configured EWMA alpha.
+func newETAEstimator() *etaEstimator {
+	return &etaEstimator{alpha: onlineDDLETAEWMAAlpha}
+}
+
+// Reset clears the smoothed rate and pending sample. Callers should reset whenever
+// throttling toggles or the migration transitions state, so stale history doesn't bias
+// the next ETA.
+func (e *etaEstimator) Reset() {
+	e.haveSample = false
+	e.smoothedRowsPerSecond = 0
+}
+
+// Sample records a (copiedRows, now) observation and folds the instantaneous rate it
+// implies into the smoothed rate.
+func (e *etaEstimator) Sample(copiedRows int64, now time.Time) {
+	if !e.haveSample {
+		e.lastSampleAt = now
+		e.lastCopiedRows = copiedRows
+		e.haveSample = true
+		return
+	}
+	elapsed := now.Sub(e.lastSampleAt).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+	instantaneousRate := float64(copiedRows-e.lastCopiedRows) / elapsed
+	if e.smoothedRowsPerSecond == 0 {
+		e.smoothedRowsPerSecond = instantaneousRate
+	} else {
+		e.smoothedRowsPerSecond = e.alpha*instantaneousRate + (1-e.alpha)*e.smoothedRowsPerSecond
+	}
+	e.lastSampleAt = now
+	e.lastCopiedRows = copiedRows
+}
+
+// RowsPerSecond returns the current smoothed copy rate.
+func (e *etaEstimator) RowsPerSecond() float64 {
+	return e.smoothedRowsPerSecond
+}
+
+// ETASeconds estimates the number of seconds remaining to copy the rest of the table. It
+// returns 0 while there is no positive smoothed rate to divide by; otherwise the estimate
+// is clamped to at least 1 second, so it is never zero or negative.
+func (e *etaEstimator) ETASeconds(copiedRows int64, totalRowsEstimate int64) int64 {
+	if e.smoothedRowsPerSecond <= 0 {
+		return 0
+	}
+	remainingRows := totalRowsEstimate - copiedRows
+	if remainingRows <= 0 {
+		return 1
+	}
+	eta := int64(float64(remainingRows) / e.smoothedRowsPerSecond)
+	if eta < 1 {
+		eta = 1
+	}
+	return eta
+}
+
+// Snapshot produces the MigrationProgress row fields for the current smoothed rate, as of
+// now. A migration with no usable sample yet reports a zero MigrationProgress.
+func (e *etaEstimator) Snapshot(now time.Time, copiedRows, totalRowsEstimate int64) MigrationProgress {
+	if e.smoothedRowsPerSecond <= 0 {
+		return MigrationProgress{}
+	}
+	etaSeconds := e.ETASeconds(copiedRows, totalRowsEstimate)
+	return MigrationProgress{
+		RowsCopiedPerSecond:       e.smoothedRowsPerSecond,
+		EstimatedSecondsRemaining: etaSeconds,
+		ETATimestamp:              now.Add(time.Duration(etaSeconds) * time.Second),
+	}
+}
@@ -0,0 +1,623 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package onlineddl
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// migrationState is the executor's in-memory, per-migration-UUID bookkeeping: the pieces of
+// state that must survive across scheduler ticks for a single running migration (copy-rate
+// estimation, retry/backoff bookkeeping, cut-over scheduling, ...), as opposed to the
+// cluster-wide policies below which are shared by every migration.
+type migrationState struct {
+	eta *etaEstimator
+
+	// rowsEstimate and rowsEstimateSource are set once by estimateRows, before copy begins,
+	// and read back by migrationProgress when computing ETASeconds.
+	rowsEstimate       int64
+	rowsEstimateSource CardinalitySource
+
+	// enumToVarchar translates this migration's source ENUM column's ordinals to their
+	// string labels during row copy, when the migration was submitted under
+	// --allow-enum-to-varchar and converts that column to VARCHAR/TEXT. nil for every other
+	// migration.
+	enumToVarchar *enumToVarcharTranslator
+
+	// cutOverWindow restricts this migration's cut-over to the --cut-over-schedule window, if
+	// one was set at submission time. nil means cut-over may be attempted at any time.
+	cutOverWindow *CutOverWindow
+	// cutOverAttempts counts how many times shouldAttemptCutOver has deferred cut-over for
+	// being outside cutOverWindow, feeding NextAttemptAt's exponential backoff.
+	cutOverAttempts int
+
+	// retryPolicy governs handleMigrationFailure's retry/backoff decision for this migration,
+	// as parsed from its --retry-on-failure/--max-retries/--retry-backoff DDL strategy flags.
+	// nil means the migration never automatically retries.
+	retryPolicy   *RetryPolicy
+	retryAttempts []RetryAttempt
+
+	// snapshotDestinationURL is this migration's --snapshot-to destination, if set, and
+	// snapshot is the TableSnapshot taken for it at cut-over time (nil until then).
+	snapshotDestinationURL string
+	snapshot               *TableSnapshot
+
+	// renameSteps is the rename chain planned by PlanAtomicRename for migrationUUID, used by
+	// RevertAtomicRename to invert it.
+	renameSteps []RenameStep
+}
+
+// Executor drives the keyspace/shard's Online DDL migrations through their lifecycle,
+// threading the pluggable policies defined elsewhere in this package into the scheduler tick
+// and cut-over paths that actually touch a running migration.
+type Executor struct {
+	keyspace, shard string
+
+	mu         sync.Mutex
+	migrations map[string]*migrationState
+	isOpen     bool
+
+	// migrationDAG tracks --migration-group/--depends-on ordering across every migration this
+	// executor has ever seen, and migrationOrder is the deterministic submission order
+	// EligibleMigrations walks to produce its result.
+	migrationDAG   *MigrationDAG
+	migrationOrder []string
+
+	// batches records every batch submitted via SubmitBatch, keyed by batch UUID, so a later
+	// RevertBatch call can look up its membership.
+	batches map[string]MigrationBatch
+
+	// retentionPolicy governs reconcileArtifacts' keep/clean-up decisions. Defaults to
+	// DefaultRetentionPolicy; override via SetRetentionPolicy.
+	retentionPolicy *RetentionPolicy
+
+	// driftDetector tracks schema drift across every declaratively-owned table in this
+	// keyspace/shard. Defaults to DriftRepairModeObserve; override via SetDriftRepairMode.
+	driftDetector *DriftDetector
+}
+
+// NewExecutor creates an Executor for the given keyspace/shard.
+func NewExecutor(keyspace, shard string) *Executor {
+	return &Executor{
+		keyspace:        keyspace,
+		shard:           shard,
+		migrations:      map[string]*migrationState{},
+		migrationDAG:    NewMigrationDAG(),
+		batches:         map[string]MigrationBatch{},
+		retentionPolicy: DefaultRetentionPolicy(),
+		driftDetector:   NewDriftDetector(DriftRepairModeObserve),
+	}
+}
+
+// SetDriftRepairMode replaces the executor's schema-drift detector with a freshly-created one
+// operating in mode, discarding any previously recorded drift events/quarantines.
+func (e *Executor) SetDriftRepairMode(mode DriftRepairMode) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.driftDetector = NewDriftDetector(mode)
+}
+
+// SetRetentionPolicy replaces the executor's artifact-retention policy, overriding
+// DefaultRetentionPolicy.
+func (e *Executor) SetRetentionPolicy(policy *RetentionPolicy) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.retentionPolicy = policy
+}
+
+// reconcileArtifacts decides which of artifacts should be kept, consulting the executor's
+// retentionPolicy (or its disk-pressure override when diskPressure is true). The caller
+// (a background reconciler) is responsible for actually dropping the ghost/shadow tables of
+// every artifact whose decision comes back Keep: false.
+func (e *Executor) reconcileArtifacts(now time.Time, artifacts []ArtifactRecord, diskPressure bool) map[string]RetentionDecision {
+	e.mu.Lock()
+	policy := e.retentionPolicy
+	e.mu.Unlock()
+	return policy.EvaluateUnderDiskPressure(now, artifacts, diskPressure)
+}
+
+// state returns (creating if necessary) the bookkeeping for migrationUUID.
+func (e *Executor) state(migrationUUID string) *migrationState {
+	state, ok := e.migrations[migrationUUID]
+	if !ok {
+		state = &migrationState{}
+		e.migrations[migrationUUID] = state
+	}
+	return state
+}
+
+// forgetMigration drops a completed/failed migration's bookkeeping, so the executor's maps
+// don't grow unbounded across a tablet's lifetime.
+func (e *Executor) forgetMigration(migrationUUID string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.migrations, migrationUUID)
+}
+
+// recordCopyProgress samples migrationUUID's copy-rate EWMA with the latest copiedRows
+// observed during its row-copy phase, creating the migration's estimator on first use. It
+// should be called once per migration-check-interval tick while the migration is copying.
+func (e *Executor) recordCopyProgress(migrationUUID string, copiedRows int64, now time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	state := e.state(migrationUUID)
+	if state.eta == nil {
+		state.eta = newETAEstimator()
+	}
+	state.eta.Sample(copiedRows, now)
+}
+
+// planEnumToVarcharColumn validates and installs the ENUM->VARCHAR/TEXT translation for
+// migrationUUID's copy phase, rejecting the migration up-front (before any row is copied) if
+// targetVarcharLength is too short to hold the longest enum label.
+func (e *Executor) planEnumToVarcharColumn(migrationUUID, enumColumnType string, targetVarcharLength int) error {
+	translator, err := newEnumToVarcharTranslator(enumColumnType)
+	if err != nil {
+		return err
+	}
+	if err := translator.ValidateTargetLength(targetVarcharLength); err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.state(migrationUUID).enumToVarchar = translator
+	return nil
+}
+
+// translateCopiedValue converts a single copied row's column value for migrationUUID's
+// ENUM->VARCHAR/TEXT column, as planned by planEnumToVarcharColumn. It returns an error for
+// a migration with no such translation planned.
+func (e *Executor) translateCopiedValue(migrationUUID string, rawValue int64) (string, error) {
+	e.mu.Lock()
+	translator := e.state(migrationUUID).enumToVarchar
+	e.mu.Unlock()
+	if translator == nil {
+		return "", fmt.Errorf("onlineddl: migration %s has no enum-to-varchar translation planned", migrationUUID)
+	}
+	return translator.Translate(rawValue)
+}
+
+// Open starts the executor's scheduler loop for a tablet that has just become primary.
+// Stale gauge values left behind by a crashed former primary are zeroed before anything is
+// repopulated, since cleanStaleMetrics can only zero keys this process remembers setting.
+func (e *Executor) Open() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	cleanStaleMetrics()
+	e.isOpen = true
+}
+
+// Close stops the executor's scheduler loop, e.g. because this tablet is no longer primary.
+// Gauges are zeroed on the way out so a dashboard doesn't keep showing this shard's last
+// counts against a scheduler that is no longer running.
+func (e *Executor) Close() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.isOpen {
+		return
+	}
+	cleanStaleMetrics()
+	e.isOpen = false
+}
+
+// refreshSchedulerGauges publishes the current queued/running/ready-to-complete/throttled
+// migration counts for this executor's keyspace/shard. It is called once per scheduler tick.
+func (e *Executor) refreshSchedulerGauges(queued, running, readyToComplete, throttled int64) {
+	setSchedulerGauge(queuedMigrationsGauge, e.keyspace, e.shard, queued)
+	setSchedulerGauge(runningMigrationsGauge, e.keyspace, e.shard, running)
+	setSchedulerGauge(readyToCompleteMigrationsGauge, e.keyspace, e.shard, readyToComplete)
+	setSchedulerGauge(throttledMigrationsGauge, e.keyspace, e.shard, throttled)
+}
+
+// estimateRows resolves and records migrationUUID's row-count estimate ahead of its copy
+// phase, preferring a live probe over schemaEstimate and falling back to the cardinality
+// floor, so downstream ETA/progress math never divides by (or reports against) zero rows.
+func (e *Executor) estimateRows(ctx context.Context, migrationUUID string, schemaEstimate int64, probe rowCountProbe) (int64, CardinalitySource) {
+	rowsEstimate, source := ResolveRowsEstimate(ctx, schemaEstimate, probe)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	state := e.state(migrationUUID)
+	state.rowsEstimate = rowsEstimate
+	state.rowsEstimateSource = source
+	return rowsEstimate, source
+}
+
+// planCutOverWindow parses and stores migrationUUID's --cut-over-schedule window, so later
+// shouldAttemptCutOver calls restrict its cut-over attempts to that window. An empty spec
+// clears any previously planned window (cut-over may be attempted at any time).
+func (e *Executor) planCutOverWindow(migrationUUID, spec string) error {
+	if spec == "" {
+		e.mu.Lock()
+		e.state(migrationUUID).cutOverWindow = nil
+		e.mu.Unlock()
+		return nil
+	}
+	window, err := ParseCutOverWindow(spec)
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.state(migrationUUID).cutOverWindow = window
+	return nil
+}
+
+// shouldAttemptCutOver reports whether migrationUUID may attempt cut-over right now. When it
+// is outside its planned cut-over window, it returns false along with the next attempt time
+// (exponential backoff keyed off how many times this migration has already been deferred);
+// the scheduler should re-check at nextAttempt rather than busy-polling.
+func (e *Executor) shouldAttemptCutOver(migrationUUID string, now time.Time) (ready bool, nextAttempt time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	state := e.state(migrationUUID)
+	if state.cutOverWindow == nil || state.cutOverWindow.IsWithinWindow(now) {
+		state.cutOverAttempts = 0
+		return true, time.Time{}
+	}
+	nextAttempt = NextAttemptAt(now, state.cutOverAttempts)
+	state.cutOverAttempts++
+	return false, nextAttempt
+}
+
+// migrationProgress returns migrationUUID's MigrationProgress row fields
+// (rows_copied_per_second/estimated_seconds_remaining/eta_timestamp) as of now, for
+// SHOW VITESS_MIGRATIONS / ReadMigrations to surface. copiedRows is the latest value passed
+// to recordCopyProgress; a migration with no copy samples yet, or whose rows estimate hasn't
+// been resolved, reports a zero MigrationProgress.
+func (e *Executor) migrationProgress(migrationUUID string, copiedRows int64, now time.Time) MigrationProgress {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	state := e.state(migrationUUID)
+	if state.eta == nil || state.rowsEstimate <= 0 {
+		return MigrationProgress{}
+	}
+	return state.eta.Snapshot(now, copiedRows, state.rowsEstimate)
+}
+
+// planRetryPolicy installs migrationUUID's automatic-retry policy, as parsed from its
+// --retry-on-failure/--max-retries/--retry-backoff DDL strategy flags.
+func (e *Executor) planRetryPolicy(migrationUUID string, policy *RetryPolicy) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.state(migrationUUID).retryPolicy = policy
+}
+
+// handleMigrationFailure decides whether the scheduler should automatically re-enqueue
+// migrationUUID after failing with failureMessage, per its planned RetryPolicy, recording the
+// retry attempt when it does. It returns shouldRetry=false with a zero backoff for a migration
+// with no retry policy planned, or once ShouldRetry reports the failure is terminal or retries
+// are exhausted.
+func (e *Executor) handleMigrationFailure(migrationUUID, failureMessage string, now time.Time) (shouldRetry bool, backoff time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	state := e.state(migrationUUID)
+	if state.retryPolicy == nil {
+		return false, 0
+	}
+	attemptsSoFar := len(state.retryAttempts)
+	if !state.retryPolicy.ShouldRetry(failureMessage, attemptsSoFar) {
+		return false, 0
+	}
+	state.retryAttempts = RecordAttempt(state.retryAttempts, now, failureMessage)
+	return true, state.retryPolicy.NextBackoff(attemptsSoFar)
+}
+
+// nextEligibleMigration picks which of queued (not yet running) should start next, according
+// to the cluster-wide GetMigrationSchedulingPolicy, also filtering queued down to migrations
+// whose MigrationDAG dependencies are satisfied (eligibleMigrations). Returns "" if nothing is
+// eligible to run right now.
+func (e *Executor) nextEligibleMigration(queued []QueuedMigration, running []QueuedMigration, completed map[string]bool) string {
+	order := make([]string, len(queued))
+	byUUID := make(map[string]QueuedMigration, len(queued))
+	for i, migration := range queued {
+		order[i] = migration.UUID
+		byUUID[migration.UUID] = migration
+	}
+	e.mu.Lock()
+	dagEligible := e.migrationDAG.EligibleMigrations(order, completed)
+	e.mu.Unlock()
+
+	var schedulable []QueuedMigration
+	for _, uuid := range dagEligible {
+		schedulable = append(schedulable, byUUID[uuid])
+	}
+	return GetMigrationSchedulingPolicy().NextEligible(schedulable, running)
+}
+
+// checkDrift compares table's last-applied declarative CREATE statement against its current
+// live definition via the executor's DriftDetector, returning the resulting DriftEvent (nil if
+// no drift) and whether the caller should enqueue an auto-repair migration.
+func (e *Executor) checkDrift(table, declaredCreate, liveCreate string, diff func(a, b string) string, now time.Time) (*DriftEvent, bool) {
+	e.mu.Lock()
+	detector := e.driftDetector
+	e.mu.Unlock()
+	return detector.Check(table, declaredCreate, liveCreate, diff, now)
+}
+
+// isTableQuarantined reports whether table is currently locked out of declarative migrations
+// by the executor's DriftDetector.
+func (e *Executor) isTableQuarantined(table string) bool {
+	e.mu.Lock()
+	detector := e.driftDetector
+	e.mu.Unlock()
+	return detector.IsQuarantined(table)
+}
+
+// ApplySchemaManifest implements `vtctldclient ApplySchemaManifest`: it computes the ordered
+// CREATE/ALTER/DROP migrations needed to converge the live schema to manifest via
+// PlanSchemaManifest, then submits them as a single batch via SubmitBatch so they cut over (and
+// revert) together.
+func (e *Executor) ApplySchemaManifest(
+	manifest []ManifestTable,
+	liveTables map[string]bool,
+	liveCreateStatements map[string]string,
+	diffStatement func(table, liveCreateStatement, desiredCreateStatement string) (string, error),
+	singletonContext string,
+	strategy string,
+	submit migrationSubmitFunc,
+) (plan *SchemaManifestPlan, batchUUID string, migrationUUIDs []string, err error) {
+	plan, err = PlanSchemaManifest(manifest, liveTables, liveCreateStatements, diffStatement, singletonContext)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	if len(plan.Migrations) == 0 {
+		return plan, "", nil, nil
+	}
+	statements := make([]string, len(plan.Migrations))
+	for i, migration := range plan.Migrations {
+		statements[i] = migration.Statement
+	}
+	batchUUID, migrationUUIDs, err = e.SubmitBatch(statements, strategy, submit)
+	return plan, batchUUID, migrationUUIDs, err
+}
+
+// BuildAndPlanFKMigrationGroup discovers migrationUUID's parent table's FK-children, assembles
+// the FKMigrationGroup, and plans the resulting cut-over as a single atomic rename chain via
+// PlanAtomicRename, so the parent and every FK-child swap into place together inside one
+// metadata-lock window.
+func (e *Executor) BuildAndPlanFKMigrationGroup(
+	migrationUUID, parentTable string,
+	discover FKChildDiscoverer,
+	namer GhostTableNamer,
+	archiveSuffix func(table string) string,
+	tableExists func(keyspace, table string) (bool, error),
+) (*FKMigrationGroup, []RenameStep, error) {
+	group, err := BuildFKMigrationGroup(migrationUUID, parentTable, discover, namer)
+	if err != nil {
+		return nil, nil, err
+	}
+	steps, err := e.PlanAtomicRename(migrationUUID, group.CutOverRenames(archiveSuffix), tableExists)
+	if err != nil {
+		return nil, nil, err
+	}
+	return group, steps, nil
+}
+
+// PlanAtomicRename implements atomic multi-table `RENAME TABLE` as a first-class Online DDL
+// operation: it plans migrationUUID's rename chain via PlanRenameChain and remembers the result
+// so a later RevertAtomicRename call can undo it step-for-step.
+func (e *Executor) PlanAtomicRename(migrationUUID string, renames []TableRename, tableExists func(keyspace, table string) (bool, error)) ([]RenameStep, error) {
+	steps, err := PlanRenameChain(renames, tableExists)
+	if err != nil {
+		return nil, err
+	}
+	e.mu.Lock()
+	e.state(migrationUUID).renameSteps = steps
+	e.mu.Unlock()
+	return steps, nil
+}
+
+// RevertAtomicRename reverts migrationUUID's previously planned rename chain, in reverse order
+// end-to-end, via InvertRenameChain.
+func (e *Executor) RevertAtomicRename(migrationUUID string) ([]RenameStep, error) {
+	e.mu.Lock()
+	steps := e.state(migrationUUID).renameSteps
+	e.mu.Unlock()
+	if steps == nil {
+		return nil, fmt.Errorf("onlineddl: migration %s has no planned rename chain to revert", migrationUUID)
+	}
+	return InvertRenameChain(steps), nil
+}
+
+// ApplyDeclarativeSchema implements `-declarative` whole-schema application: it computes the
+// statements needed to reconcile currentSchemaSQL with request's desired schema via
+// PlanDeclarativeSchema, then (unless the plan is a noop) submits them as a single batch via
+// SubmitBatch so they cut over together.
+func (e *Executor) ApplyDeclarativeSchema(
+	request DeclarativeSchemaRequest,
+	currentSchemaSQL, singletonContext string,
+	diff SchemaDiffFunc,
+	strategy string,
+	submit migrationSubmitFunc,
+) (plan *DeclarativeSchemaPlan, batchUUID string, migrationUUIDs []string, err error) {
+	plan, err = PlanDeclarativeSchema(request, currentSchemaSQL, singletonContext, diff)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	if plan.IsNoop() {
+		return plan, "", nil, nil
+	}
+	batchUUID, migrationUUIDs, err = e.SubmitBatch(plan.Statements, strategy, submit)
+	return plan, batchUUID, migrationUUIDs, err
+}
+
+// SubmitBatch implements `VITESS_MIGRATION_BATCH '<ddl1>; <ddl2>; ...'`: it submits every
+// statement via submit and remembers the resulting batch's membership so a later RevertBatch
+// call can revert the whole thing as a unit.
+func (e *Executor) SubmitBatch(statements []string, strategy string, submit migrationSubmitFunc) (batchUUID string, migrationUUIDs []string, err error) {
+	batchUUID, migrationUUIDs, err = SubmitMigrationBatch(statements, strategy, submit)
+	e.mu.Lock()
+	e.batches[batchUUID] = MigrationBatch{BatchUUID: batchUUID, MigrationUUIDs: migrationUUIDs}
+	e.mu.Unlock()
+	if err == nil && strings.Contains(strategy, DependencyOrderCompletionStrategyFlag) {
+		e.registerDependencyOrderedBatch(migrationUUIDs, statements)
+	}
+	return batchUUID, migrationUUIDs, err
+}
+
+// registerDependencyOrderedBatch parses statements' schema references and merges the
+// resulting dependency edges into the executor's single shared MigrationDAG, for a batch
+// submitted under --dependency-order-completion. migrationUUIDs and statements must be the
+// same length and in the same (submission) order.
+func (e *Executor) registerDependencyOrderedBatch(migrationUUIDs []string, statements []string) {
+	statementByUUID := make(map[string]string, len(migrationUUIDs))
+	for i, uuid := range migrationUUIDs {
+		statementByUUID[uuid] = statements[i]
+	}
+	dag := BuildDependencyDAG(migrationUUIDs, statementByUUID)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, uuid := range migrationUUIDs {
+		e.migrationDAG.AddMigration(uuid, dag.group[uuid], dag.dependsOn[uuid])
+		e.migrationOrder = append(e.migrationOrder, uuid)
+	}
+}
+
+// RevertBatch implements `REVERT VITESS_MIGRATION_BATCH '<batch-uuid>'`, looking up the
+// batch's membership previously recorded by SubmitBatch.
+func (e *Executor) RevertBatch(batchUUID string, canRevert func(migrationUUID string) (bool, error), revert migrationRevertFunc) error {
+	e.mu.Lock()
+	batch, ok := e.batches[batchUUID]
+	e.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("onlineddl: unknown migration batch %q", batchUUID)
+	}
+	return RevertMigrationBatch(batch, canRevert, revert)
+}
+
+// registerMigrationDependencies records migrationUUID's --migration-group/--depends-on
+// relationship at submission time, so a later scheduler tick's eligibleMigrations/
+// groupReadyToCutOver calls see it.
+func (e *Executor) registerMigrationDependencies(migrationUUID, migrationGroup string, dependsOn []string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.migrationDAG.AddMigration(migrationUUID, migrationGroup, dependsOn)
+	e.migrationOrder = append(e.migrationOrder, migrationUUID)
+}
+
+// eligibleMigrations returns every registered, not-yet-completed migration whose
+// --depends-on predecessors have all completed, in submission order. The scheduler tick calls
+// this to decide which queued migrations may move to the next phase this round.
+func (e *Executor) eligibleMigrations(completed map[string]bool) []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.migrationDAG.EligibleMigrations(e.migrationOrder, completed)
+}
+
+// groupReadyToCutOver reports whether every migration in migrationGroup is ready_to_complete,
+// so the scheduler can cut the whole group over atomically rather than one migration at a
+// time.
+func (e *Executor) groupReadyToCutOver(migrationGroup string, readyToComplete map[string]bool) (bool, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.migrationDAG.GroupReadyToCutOver(migrationGroup, readyToComplete)
+}
+
+// drainForCutOver gracefully drains migrationUUID's blocking connections ahead of cut-over,
+// per --cut-over-drain-timeout/--cut-over-drain-grace-period: see the package-level
+// drainForCutOver for the kill-query-then-escalate behavior. openConns/killQuery/killConnection
+// are the caller's tablet query-engine hooks.
+func (e *Executor) drainForCutOver(
+	ctx context.Context,
+	migrationUUID string,
+	deadline time.Time,
+	gracePeriod time.Duration,
+	openConns func() []cutOverConnection,
+	killQuery func(connID int64) error,
+	killConnection func(connID int64) error,
+) error {
+	if err := drainForCutOver(ctx, deadline, gracePeriod, openConns, killQuery, killConnection); err != nil {
+		return fmt.Errorf("onlineddl: migration %s: draining for cut-over: %w", migrationUUID, err)
+	}
+	return nil
+}
+
+// planSnapshotDestination installs migrationUUID's --snapshot-to destination, so
+// takeSnapshotAtCutOver knows where (and whether) to write a pre-migration snapshot.
+func (e *Executor) planSnapshotDestination(migrationUUID, destinationURL string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.state(migrationUUID).snapshotDestinationURL = destinationURL
+}
+
+// takeSnapshotAtCutOver writes migrationUUID's pre-migration snapshot via writer, if
+// --snapshot-to was set for it, remembering the result for a later RevertFromSnapshot. It is a
+// noop (returns nil, nil) for a migration with no snapshot destination planned.
+func (e *Executor) takeSnapshotAtCutOver(ctx context.Context, migrationUUID, table string, writer SnapshotWriter) (*TableSnapshot, error) {
+	e.mu.Lock()
+	destinationURL := e.state(migrationUUID).snapshotDestinationURL
+	e.mu.Unlock()
+	if destinationURL == "" {
+		return nil, nil
+	}
+	snapshot, err := TakeSnapshot(ctx, writer, migrationUUID, table, destinationURL)
+	if err != nil {
+		return nil, err
+	}
+	e.mu.Lock()
+	e.state(migrationUUID).snapshot = snapshot
+	e.mu.Unlock()
+	return snapshot, nil
+}
+
+// revertMigrationFromSnapshot implements `RevertMigration --from-snapshot=<uuid>` for a
+// migration that took a pre-migration snapshot via takeSnapshotAtCutOver, restoring it and
+// replaying binlog events forward to targetGTID.
+func (e *Executor) revertMigrationFromSnapshot(ctx context.Context, migrationUUID string, restorer SnapshotRestorer, replayer BinlogReplayer, targetGTID string) error {
+	e.mu.Lock()
+	snapshot := e.state(migrationUUID).snapshot
+	e.mu.Unlock()
+	if snapshot == nil {
+		return fmt.Errorf("onlineddl: migration %s has no snapshot to revert from", migrationUUID)
+	}
+	return RevertFromSnapshot(ctx, restorer, replayer, *snapshot, targetGTID)
+}
+
+// waitForExternalReplication blocks cut-over until the external consumer registered under
+// --external-replication-barrier=<barrierName> has caught up to gtid, if one was configured
+// for this migration. An empty barrierName is a noop (no external consumer to wait for).
+func (e *Executor) waitForExternalReplication(ctx context.Context, barrierName, gtid string) error {
+	if barrierName == "" {
+		return nil
+	}
+	barrier, err := GetExternalReplicationBarrier(barrierName)
+	if err != nil {
+		return err
+	}
+	return barrier.WaitForCatchup(ctx, gtid)
+}
+
+// cutOver commits migrationUUID's cut-over (the caller has already done the actual
+// metadata-locking table swap) and notifies any registered CutOverNotifier so subscribers
+// (e.g. vtgate's plan cache) can selectively invalidate just the affected tables. If
+// barrierName names a registered ExternalReplicationBarrier, cut-over blocks until it reports
+// the external consumer has caught up to cutOverGTID before committing.
+func (e *Executor) cutOver(ctx context.Context, migrationUUID string, tables []string, barrierName, cutOverGTID string) error {
+	if err := e.waitForExternalReplication(ctx, barrierName, cutOverGTID); err != nil {
+		return fmt.Errorf("onlineddl: migration %s: %w", migrationUUID, err)
+	}
+	NotifyCutOver(e.keyspace, e.shard, tables)
+	e.forgetMigration(migrationUUID)
+	return nil
+}
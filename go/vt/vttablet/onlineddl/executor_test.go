@@ -0,0 +1,586 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package onlineddl
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutorRecordCopyProgress(t *testing.T) {
+	executor := NewExecutor("ks", "0")
+	start := time.Now()
+
+	executor.recordCopyProgress("uuid1", 0, start)
+	executor.recordCopyProgress("uuid1", 1000, start.Add(time.Second))
+
+	require.Contains(t, executor.migrations, "uuid1")
+	assert.Greater(t, executor.migrations["uuid1"].eta.RowsPerSecond(), float64(0))
+
+	// A second migration gets its own, independent estimator.
+	executor.recordCopyProgress("uuid2", 0, start)
+	assert.NotSame(t, executor.migrations["uuid1"].eta, executor.migrations["uuid2"].eta)
+}
+
+func TestExecutorBuildAndPlanFKMigrationGroup(t *testing.T) {
+	executor := NewExecutor("ks", "0")
+	discover := func(parentTable string) ([]ForeignKeyChild, error) {
+		return []ForeignKeyChild{{Table: "child1", ConstraintName: "fk1", Columns: []string{"parent_id"}, ParentColumns: []string{"id"}}}, nil
+	}
+	namer := func(table string) string { return "_" + table + "_gho" }
+	archiveSuffix := func(table string) string { return table + "_del" }
+	existing := map[string]bool{"parent": true, "child1": true, "_parent_gho": true, "_child1_gho": true}
+	tableExists := func(keyspace, table string) (bool, error) { return existing[table], nil }
+
+	group, steps, err := executor.BuildAndPlanFKMigrationGroup("uuid1", "parent", discover, namer, archiveSuffix, tableExists)
+	require.NoError(t, err)
+	assert.Equal(t, "parent", group.Parent)
+	require.Len(t, group.Children, 1)
+	assert.Equal(t, "child1", group.Children[0].Table)
+	require.NotEmpty(t, steps)
+
+	// The plan the executor stored for revert must be exactly what was returned.
+	reverted, err := executor.RevertAtomicRename("uuid1")
+	require.NoError(t, err)
+	assert.Equal(t, InvertRenameChain(steps), reverted)
+}
+
+func TestExecutorCheckDrift(t *testing.T) {
+	executor := NewExecutor("ks", "0")
+	diff := func(a, b string) string { return "diff" }
+	now := time.Now()
+
+	t.Run("default mode observes drift without requesting repair", func(t *testing.T) {
+		event, shouldRepair := executor.checkDrift("t1", "create table t1 (id int)", "create table t1 (id int, c int)", diff, now)
+		require.NotNil(t, event)
+		assert.False(t, shouldRepair)
+		assert.False(t, executor.isTableQuarantined("t1"))
+	})
+
+	t.Run("quarantine mode locks the table out until acknowledged", func(t *testing.T) {
+		executor.SetDriftRepairMode(DriftRepairModeQuarantine)
+		_, shouldRepair := executor.checkDrift("t1", "create table t1 (id int)", "create table t1 (id int, c int)", diff, now)
+		assert.False(t, shouldRepair)
+		assert.True(t, executor.isTableQuarantined("t1"))
+	})
+
+	t.Run("auto-repair mode requests repair", func(t *testing.T) {
+		executor.SetDriftRepairMode(DriftRepairModeAutoRepair)
+		_, shouldRepair := executor.checkDrift("t1", "create table t1 (id int)", "create table t1 (id int, c int)", diff, now)
+		assert.True(t, shouldRepair)
+	})
+}
+
+func TestExecutorApplySchemaManifest(t *testing.T) {
+	executor := NewExecutor("ks", "0")
+	manifest := []ManifestTable{{Name: "t1", CreateStatement: "create table t1 (id int)"}}
+	diffStatement := func(table, liveCreateStatement, desiredCreateStatement string) (string, error) {
+		return "", nil
+	}
+
+	t.Run("noop plan submits nothing", func(t *testing.T) {
+		liveTables := map[string]bool{"t1": true}
+		plan, batchUUID, migrationUUIDs, err := executor.ApplySchemaManifest(manifest, liveTables, nil, diffStatement, "", "", nil)
+		require.NoError(t, err)
+		assert.Empty(t, plan.Migrations)
+		assert.Empty(t, batchUUID)
+		assert.Empty(t, migrationUUIDs)
+	})
+
+	t.Run("a missing table is created and submitted as a batch", func(t *testing.T) {
+		var submittedStatements []string
+		submit := func(statement, strategy string) (string, error) {
+			submittedStatements = append(submittedStatements, statement)
+			return "uuid-" + statement, nil
+		}
+		plan, batchUUID, migrationUUIDs, err := executor.ApplySchemaManifest(manifest, map[string]bool{}, nil, diffStatement, "ctx1", "online", submit)
+		require.NoError(t, err)
+		require.Len(t, plan.Migrations, 1)
+		assert.Equal(t, ManifestActionCreate, plan.Migrations[0].Action)
+		assert.NotEmpty(t, batchUUID)
+		assert.Len(t, migrationUUIDs, 1)
+		assert.Equal(t, []string{plan.Migrations[0].Statement}, submittedStatements)
+	})
+}
+
+func TestExecutorPlanAndRevertAtomicRename(t *testing.T) {
+	executor := NewExecutor("ks", "0")
+	existingTables := map[string]bool{"a": true, "b": true}
+	tableExists := func(keyspace, table string) (bool, error) { return existingTables[table], nil }
+
+	t.Run("reverting before planning fails", func(t *testing.T) {
+		_, err := executor.RevertAtomicRename("uuid-unplanned")
+		require.Error(t, err)
+	})
+
+	renames := []TableRename{{FromTable: "a", ToTable: "b"}, {FromTable: "b", ToTable: "a"}}
+	steps, err := executor.PlanAtomicRename("uuid1", renames, tableExists)
+	require.NoError(t, err)
+	require.NotEmpty(t, steps)
+
+	reverted, err := executor.RevertAtomicRename("uuid1")
+	require.NoError(t, err)
+	assert.Equal(t, InvertRenameChain(steps), reverted)
+}
+
+type fakeSnapshotWriter struct {
+	gtidPosition string
+}
+
+func (w fakeSnapshotWriter) WriteSnapshot(ctx context.Context, destinationURL, table string) (string, error) {
+	return w.gtidPosition, nil
+}
+
+type fakeSnapshotRestorer struct {
+	restored []string
+}
+
+func (r *fakeSnapshotRestorer) RestoreSnapshot(ctx context.Context, storageURL, table string) error {
+	r.restored = append(r.restored, storageURL+"/"+table)
+	return nil
+}
+
+type fakeBinlogReplayer struct {
+	replayed []string
+}
+
+func (r *fakeBinlogReplayer) ReplayBetween(ctx context.Context, table, fromGTID, toGTID string) error {
+	r.replayed = append(r.replayed, table+":"+fromGTID+"->"+toGTID)
+	return nil
+}
+
+func TestExecutorSnapshotAndRevert(t *testing.T) {
+	executor := NewExecutor("ks", "0")
+
+	t.Run("no destination planned takes no snapshot", func(t *testing.T) {
+		snapshot, err := executor.takeSnapshotAtCutOver(context.Background(), "uuid-no-snapshot", "t1", fakeSnapshotWriter{})
+		require.NoError(t, err)
+		assert.Nil(t, snapshot)
+	})
+
+	executor.planSnapshotDestination("uuid1", "s3://bucket/path")
+	snapshot, err := executor.takeSnapshotAtCutOver(context.Background(), "uuid1", "t1", fakeSnapshotWriter{gtidPosition: "MySQL56/abc"})
+	require.NoError(t, err)
+	require.NotNil(t, snapshot)
+	assert.Equal(t, "MySQL56/abc", snapshot.GTIDPosition)
+
+	t.Run("reverting without a snapshot fails", func(t *testing.T) {
+		err := executor.revertMigrationFromSnapshot(context.Background(), "uuid-no-snapshot", nil, nil, "MySQL56/def")
+		require.Error(t, err)
+	})
+
+	t.Run("reverting restores and replays forward to the target GTID", func(t *testing.T) {
+		restorer := &fakeSnapshotRestorer{}
+		replayer := &fakeBinlogReplayer{}
+		err := executor.revertMigrationFromSnapshot(context.Background(), "uuid1", restorer, replayer, "MySQL56/def")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"s3://bucket/path/t1"}, restorer.restored)
+		assert.Equal(t, []string{"t1:MySQL56/abc->MySQL56/def"}, replayer.replayed)
+	})
+}
+
+func TestExecutorApplyDeclarativeSchema(t *testing.T) {
+	executor := NewExecutor("ks", "0")
+	diff := func(currentSchemaSQL, desiredSchemaSQL string) ([]string, error) {
+		if currentSchemaSQL == desiredSchemaSQL {
+			return nil, nil
+		}
+		return []string{"alter table t1 add column c1 int"}, nil
+	}
+
+	t.Run("noop plan submits nothing", func(t *testing.T) {
+		request := DeclarativeSchemaRequest{Keyspace: "ks", Shard: "0", DesiredSchemaSQL: "same"}
+		plan, batchUUID, migrationUUIDs, err := executor.ApplyDeclarativeSchema(request, "same", "", diff, "", nil)
+		require.NoError(t, err)
+		assert.True(t, plan.IsNoop())
+		assert.Empty(t, batchUUID)
+		assert.Empty(t, migrationUUIDs)
+	})
+
+	t.Run("a real diff is submitted as a batch", func(t *testing.T) {
+		request := DeclarativeSchemaRequest{Keyspace: "ks", Shard: "0", DesiredSchemaSQL: "desired"}
+		var submittedStatements []string
+		submit := func(statement, strategy string) (string, error) {
+			submittedStatements = append(submittedStatements, statement)
+			return "uuid-" + statement, nil
+		}
+
+		plan, batchUUID, migrationUUIDs, err := executor.ApplyDeclarativeSchema(request, "current", "", diff, "online", submit)
+		require.NoError(t, err)
+		assert.False(t, plan.IsNoop())
+		assert.NotEmpty(t, batchUUID)
+		assert.Len(t, migrationUUIDs, 1)
+		assert.Equal(t, plan.Statements, submittedStatements)
+	})
+}
+
+func TestExecutorHandleMigrationFailure(t *testing.T) {
+	executor := NewExecutor("ks", "0")
+	now := time.Now()
+
+	t.Run("no retry policy planned never retries", func(t *testing.T) {
+		shouldRetry, _ := executor.handleMigrationFailure("uuid-no-policy", "errno 1213 deadlock", now)
+		assert.False(t, shouldRetry)
+	})
+
+	t.Run("transient failures retry with exponential backoff up to MaxRetries", func(t *testing.T) {
+		executor.planRetryPolicy("uuid1", &RetryPolicy{
+			RetryOn:    FailureClassTransient,
+			MaxRetries: 2,
+			Backoff:    BackoffKindExponential,
+			BaseDelay:  time.Second,
+			MaxDelay:   time.Minute,
+		})
+
+		shouldRetry, backoff := executor.handleMigrationFailure("uuid1", "errno 1213 deadlock found", now)
+		require.True(t, shouldRetry)
+		assert.Equal(t, time.Second, backoff)
+
+		shouldRetry, backoff = executor.handleMigrationFailure("uuid1", "errno 1213 deadlock found", now)
+		require.True(t, shouldRetry)
+		assert.Equal(t, 2*time.Second, backoff)
+
+		// MaxRetries exhausted.
+		shouldRetry, _ = executor.handleMigrationFailure("uuid1", "errno 1213 deadlock found", now)
+		assert.False(t, shouldRetry)
+	})
+
+	t.Run("terminal failures never retry even with a policy planned", func(t *testing.T) {
+		executor.planRetryPolicy("uuid2", &RetryPolicy{RetryOn: FailureClassTransient, MaxRetries: 5})
+		shouldRetry, _ := executor.handleMigrationFailure("uuid2", "syntax error near 'FOO'", now)
+		assert.False(t, shouldRetry)
+	})
+}
+
+func TestExecutorSubmitBatchDependencyOrderCompletion(t *testing.T) {
+	executor := NewExecutor("ks", "0")
+	statements := []string{
+		"create table t1 (id int primary key)",
+		"alter table t2 add column t1_id int, add foreign key (t1_id) references t1 (id)",
+	}
+	submit := func(statement, strategy string) (string, error) {
+		if strings.HasPrefix(statement, "create table t1 ") {
+			return "uuid-t1", nil
+		}
+		return "uuid-t2", nil
+	}
+
+	_, migrationUUIDs, err := executor.SubmitBatch(statements, DependencyOrderCompletionStrategyFlag, submit)
+	require.NoError(t, err)
+
+	// uuid-t2 references a table uuid-t1 produces, so it must not be eligible until uuid-t1
+	// has completed -- proving BuildDependencyDAG's edges actually reached the scheduler's
+	// shared MigrationDAG, not just a throwaway local one.
+	eligible := executor.eligibleMigrations(map[string]bool{})
+	assert.Equal(t, []string{"uuid-t1"}, eligible)
+
+	eligible = executor.eligibleMigrations(map[string]bool{"uuid-t1": true})
+	assert.Equal(t, migrationUUIDs[1:], eligible)
+}
+
+func TestExecutorReconcileArtifacts(t *testing.T) {
+	executor := NewExecutor("ks", "0")
+	now := time.Now()
+	artifacts := []ArtifactRecord{
+		{MigrationUUID: "uuid1", Table: "t1", Successful: true, CompletedAt: now},
+	}
+
+	t.Run("uses the default retention policy", func(t *testing.T) {
+		decisions := executor.reconcileArtifacts(now, artifacts, false)
+		assert.True(t, decisions["uuid1"].Keep)
+	})
+
+	t.Run("SetRetentionPolicy overrides KeepLastNSuccessful", func(t *testing.T) {
+		executor.SetRetentionPolicy(&RetentionPolicy{KeepLastNSuccessful: 0})
+		decisions := executor.reconcileArtifacts(now, artifacts, false)
+		assert.False(t, decisions["uuid1"].Keep)
+	})
+
+	t.Run("disk pressure forces the emergency policy regardless of configuration", func(t *testing.T) {
+		executor.SetRetentionPolicy(&RetentionPolicy{KeepLastNSuccessful: 0})
+		decisions := executor.reconcileArtifacts(now, artifacts, true)
+		assert.True(t, decisions["uuid1"].Keep)
+	})
+}
+
+func TestExecutorNextEligibleMigration(t *testing.T) {
+	executor := NewExecutor("ks", "0")
+	executor.registerMigrationDependencies("uuid1", "", nil)
+	executor.registerMigrationDependencies("uuid2", "", []string{"uuid1"})
+
+	queued := []QueuedMigration{
+		{UUID: "uuid1", Table: "t1", Action: "alter", Concurrent: true},
+		{UUID: "uuid2", Table: "t1", Action: "alter", Concurrent: true},
+	}
+
+	// uuid2 depends on uuid1, so only uuid1 is eligible even though the scheduling policy
+	// alone would be happy to run either.
+	next := executor.nextEligibleMigration(queued, nil, map[string]bool{})
+	assert.Equal(t, "uuid1", next)
+
+	// Once uuid1 has completed, uuid2 becomes eligible.
+	next = executor.nextEligibleMigration(queued, nil, map[string]bool{"uuid1": true})
+	assert.Equal(t, "uuid2", next)
+}
+
+func TestExecutorSubmitAndRevertBatch(t *testing.T) {
+	executor := NewExecutor("ks", "0")
+
+	var submitted []string
+	submit := func(statement, strategy string) (string, error) {
+		submitted = append(submitted, statement)
+		return "uuid-" + statement, nil
+	}
+	batchUUID, migrationUUIDs, err := executor.SubmitBatch([]string{"ddl1", "ddl2"}, "online", submit)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"uuid-ddl1", "uuid-ddl2"}, migrationUUIDs)
+
+	t.Run("reverting an unknown batch fails", func(t *testing.T) {
+		err := executor.RevertBatch("does-not-exist", nil, nil)
+		require.Error(t, err)
+	})
+
+	t.Run("reverting a known batch reverts its members in reverse order", func(t *testing.T) {
+		var reverted []string
+		err := executor.RevertBatch(
+			batchUUID,
+			func(migrationUUID string) (bool, error) { return true, nil },
+			func(migrationUUID string) error { reverted = append(reverted, migrationUUID); return nil },
+		)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"uuid-ddl2", "uuid-ddl1"}, reverted)
+	})
+}
+
+func TestExecutorMigrationProgress(t *testing.T) {
+	executor := NewExecutor("ks", "0")
+	start := time.Now()
+
+	t.Run("no rows estimate yet reports a zero snapshot", func(t *testing.T) {
+		executor.recordCopyProgress("uuid1", 0, start)
+		progress := executor.migrationProgress("uuid1", 0, start)
+		assert.Zero(t, progress)
+	})
+
+	t.Run("with samples and a rows estimate, reports a populated snapshot", func(t *testing.T) {
+		_, _ = executor.estimateRows(context.Background(), "uuid2", 1000, nil)
+		executor.recordCopyProgress("uuid2", 0, start)
+		executor.recordCopyProgress("uuid2", 500, start.Add(time.Second))
+
+		progress := executor.migrationProgress("uuid2", 500, start.Add(time.Second))
+		assert.Greater(t, progress.RowsCopiedPerSecond, float64(0))
+		assert.Greater(t, progress.EstimatedSecondsRemaining, int64(0))
+	})
+}
+
+func TestExecutorPlanEnumToVarcharColumn(t *testing.T) {
+	executor := NewExecutor("ks", "0")
+
+	t.Run("target too short is rejected up front", func(t *testing.T) {
+		err := executor.planEnumToVarcharColumn("uuid1", "enum('small','medium','large')", 3)
+		require.Error(t, err)
+	})
+
+	t.Run("planned translation is applied during copy", func(t *testing.T) {
+		require.NoError(t, executor.planEnumToVarcharColumn("uuid2", "enum('small','medium','large')", 10))
+
+		value, err := executor.translateCopiedValue("uuid2", 2)
+		require.NoError(t, err)
+		assert.Equal(t, "medium", value)
+	})
+
+	t.Run("migration without a planned translation errors", func(t *testing.T) {
+		_, err := executor.translateCopiedValue("uuid-unplanned", 1)
+		require.Error(t, err)
+	})
+}
+
+func TestExecutorOpenCloseTracksLifecycle(t *testing.T) {
+	executor := NewExecutor("ks", "0")
+	assert.False(t, executor.isOpen)
+
+	executor.Open()
+	assert.True(t, executor.isOpen)
+	// refreshSchedulerGauges must be safe to call once the executor is open, regardless of
+	// whether any migration has been seen yet.
+	executor.refreshSchedulerGauges(3, 2, 1, 0)
+
+	executor.Close()
+	assert.False(t, executor.isOpen)
+
+	// Closing an already-closed executor is a noop, not a second metrics reset.
+	executor.Close()
+	assert.False(t, executor.isOpen)
+}
+
+type recordingCutOverNotifier struct {
+	events []SchemaVersionEvent
+}
+
+func (r *recordingCutOverNotifier) OnCutOver(event SchemaVersionEvent) {
+	r.events = append(r.events, event)
+}
+
+func TestExecutorCutOverNotifiesAndForgetsMigration(t *testing.T) {
+	notifier := &recordingCutOverNotifier{}
+	RegisterCutOverNotifier(notifier)
+
+	executor := NewExecutor("ks", "0")
+	executor.recordCopyProgress("uuid1", 100, time.Now())
+	require.Contains(t, executor.migrations, "uuid1")
+
+	require.NoError(t, executor.cutOver(context.Background(), "uuid1", []string{"t1", "t2"}, "", ""))
+
+	require.NotEmpty(t, notifier.events)
+	last := notifier.events[len(notifier.events)-1]
+	assert.Equal(t, "ks", last.Keyspace)
+	assert.Equal(t, []string{"t1", "t2"}, last.Tables)
+	assert.NotContains(t, executor.migrations, "uuid1")
+}
+
+func TestExecutorCutOverWaitsForExternalReplicationBarrier(t *testing.T) {
+	executor := NewExecutor("ks", "0")
+	barrierPath := t.TempDir() + "/gtid"
+	NewFileExternalReplicationBarrier(barrierPath)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- executor.cutOver(context.Background(), "uuid1", nil, "file", "MySQL56/abc")
+	}()
+
+	select {
+	case err := <-errCh:
+		t.Fatalf("cutOver returned before the barrier caught up: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	require.NoError(t, os.WriteFile(barrierPath, []byte("MySQL56/abc"), 0600))
+	require.NoError(t, <-errCh)
+}
+
+func TestExecutorCutOverUnknownBarrierErrors(t *testing.T) {
+	executor := NewExecutor("ks", "0")
+	err := executor.cutOver(context.Background(), "uuid1", nil, "does-not-exist", "gtid")
+	require.Error(t, err)
+}
+
+type fakeCutOverConnection struct {
+	id        int64
+	startedAt time.Time
+}
+
+func (c fakeCutOverConnection) ID() int64                 { return c.id }
+func (c fakeCutOverConnection) QueryStartedAt() time.Time { return c.startedAt }
+
+func TestExecutorEligibleMigrationsAndGroupReadyToCutOver(t *testing.T) {
+	executor := NewExecutor("ks", "0")
+	executor.registerMigrationDependencies("uuid1", "group-a", nil)
+	executor.registerMigrationDependencies("uuid2", "group-a", []string{"uuid1"})
+	executor.registerMigrationDependencies("uuid3", "", []string{"group-a"})
+
+	completed := map[string]bool{}
+	assert.Equal(t, []string{"uuid1"}, executor.eligibleMigrations(completed))
+
+	completed["uuid1"] = true
+	assert.Equal(t, []string{"uuid2"}, executor.eligibleMigrations(completed))
+
+	ready, err := executor.groupReadyToCutOver("group-a", map[string]bool{"uuid1": true})
+	require.NoError(t, err)
+	assert.False(t, ready)
+
+	ready, err = executor.groupReadyToCutOver("group-a", map[string]bool{"uuid1": true, "uuid2": true})
+	require.NoError(t, err)
+	assert.True(t, ready)
+
+	completed["uuid2"] = true
+	assert.Equal(t, []string{"uuid3"}, executor.eligibleMigrations(completed))
+}
+
+func TestExecutorDrainForCutOver(t *testing.T) {
+	executor := NewExecutor("ks", "0")
+	now := time.Now()
+
+	conns := []cutOverConnection{fakeCutOverConnection{id: 1, startedAt: now.Add(-time.Hour)}}
+	var killedQuery, killedConnection []int64
+
+	err := executor.drainForCutOver(
+		context.Background(),
+		"uuid1",
+		now.Add(-time.Millisecond), // deadline already passed: escalate on the first tick
+		time.Minute,
+		func() []cutOverConnection { return conns },
+		func(connID int64) error { killedQuery = append(killedQuery, connID); return nil },
+		func(connID int64) error { killedConnection = append(killedConnection, connID); return nil },
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1}, killedQuery)
+	assert.Equal(t, []int64{1}, killedConnection)
+}
+
+func TestExecutorShouldAttemptCutOver(t *testing.T) {
+	executor := NewExecutor("ks", "0")
+
+	t.Run("no window planned is always ready", func(t *testing.T) {
+		ready, _ := executor.shouldAttemptCutOver("uuid-no-window", time.Now())
+		assert.True(t, ready)
+	})
+
+	t.Run("outside the window defers with backoff, inside it is ready", func(t *testing.T) {
+		require.NoError(t, executor.planCutOverWindow("uuid1", "02:00-03:00"))
+
+		outsideWindow := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+		ready, next := executor.shouldAttemptCutOver("uuid1", outsideWindow)
+		assert.False(t, ready)
+		assert.True(t, next.After(outsideWindow))
+
+		insideWindow := time.Date(2024, 1, 1, 2, 30, 0, 0, time.UTC)
+		ready, _ = executor.shouldAttemptCutOver("uuid1", insideWindow)
+		assert.True(t, ready)
+	})
+
+	t.Run("clearing the window with an empty spec makes it always ready", func(t *testing.T) {
+		require.NoError(t, executor.planCutOverWindow("uuid2", "02:00-03:00"))
+		require.NoError(t, executor.planCutOverWindow("uuid2", ""))
+
+		ready, _ := executor.shouldAttemptCutOver("uuid2", time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+		assert.True(t, ready)
+	})
+}
+
+func TestExecutorEstimateRows(t *testing.T) {
+	executor := NewExecutor("ks", "0")
+
+	t.Run("prefers a successful probe", func(t *testing.T) {
+		probe := func(ctx context.Context) (int64, error) { return 42, nil }
+		rows, source := executor.estimateRows(context.Background(), "uuid1", 100, probe)
+		assert.EqualValues(t, 42, rows)
+		assert.Equal(t, CardinalitySourceProbe, source)
+		assert.EqualValues(t, 42, executor.migrations["uuid1"].rowsEstimate)
+	})
+
+	t.Run("falls back to the floor when both are zero", func(t *testing.T) {
+		rows, source := executor.estimateRows(context.Background(), "uuid2", 0, nil)
+		assert.EqualValues(t, 1, rows)
+		assert.Equal(t, CardinalitySourceFloor, source)
+	})
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package onlineddl
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ExternalReplicationBarrier lets an external binlog consumer (Tungsten, a Debezium-based
+// mirror, ...) that Vitess has no visibility into delay an Online DDL cut-over until it has
+// caught up to a given GTID. The scheduler invokes WaitForCatchup after a migration becomes
+// ready_to_complete but before the atomic cut-over. Executor.cutOver waits under the ctx its
+// caller passes and applies no timeout of its own.
+type ExternalReplicationBarrier interface {
+	WaitForCatchup(ctx context.Context, gtid string) error
+}
+
+var (
+	externalReplicationBarriersMu sync.Mutex
+	externalReplicationBarriers   = map[string]ExternalReplicationBarrier{
+		"noop": noopExternalReplicationBarrier{},
+	}
+)
+
+// RegisterExternalReplicationBarrier makes a named barrier plugin available to the
+// --external-replication-barrier=<name> DDL strategy flag.
+func RegisterExternalReplicationBarrier(name string, barrier ExternalReplicationBarrier) {
+	externalReplicationBarriersMu.Lock()
+	defer externalReplicationBarriersMu.Unlock()
+	externalReplicationBarriers[name] = barrier
+}
+
+// GetExternalReplicationBarrier looks up a barrier plugin previously registered via
+// RegisterExternalReplicationBarrier (or one of the built-ins: "noop", "file").
+func GetExternalReplicationBarrier(name string) (ExternalReplicationBarrier, error) {
+	externalReplicationBarriersMu.Lock()
+	defer externalReplicationBarriersMu.Unlock()
+	barrier, ok := externalReplicationBarriers[name]
+	if !ok {
+		return nil, fmt.Errorf("external_replication_barrier: no barrier plugin registered under name %q", name)
+	}
+	return barrier, nil
+}
+
+// noopExternalReplicationBarrier never blocks cut-over. It is the default when
+// --external-replication-barrier is unset.
+type noopExternalReplicationBarrier struct{}
+
+func (noopExternalReplicationBarrier) WaitForCatchup(ctx context.Context, gtid string) error {
+	return nil
+}
+
+// filePollInterval is how often FileExternalReplicationBarrier re-reads its GTID file while
+// waiting for catchup.
+const filePollInterval = time.Second
+
+// FileExternalReplicationBarrier blocks cut-over until the GTID recorded in Path matches
+// the requested position, by comparing the two strings exactly after trimming surrounding
+// whitespace from the file (external consumers are expected to write the exact GTID they've
+// replayed up to).
+type FileExternalReplicationBarrier struct {
+	Path string
+}
+
+// NewFileExternalReplicationBarrier creates and registers a "file" barrier plugin reading
+// the external consumer's caught-up GTID from path.
+func NewFileExternalReplicationBarrier(path string) *FileExternalReplicationBarrier {
+	barrier := &FileExternalReplicationBarrier{Path: path}
+	RegisterExternalReplicationBarrier("file", barrier)
+	return barrier
+}
+
+// WaitForCatchup blocks until the file at Path contains gtid (exact match), or ctx is done.
+func (b *FileExternalReplicationBarrier) WaitForCatchup(ctx context.Context, gtid string) error {
+	for {
+		contents, err := os.ReadFile(b.Path)
+		if err == nil && strings.TrimSpace(string(contents)) == gtid {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("external_replication_barrier: timed out waiting for %s to reach gtid %s: %w", b.Path, gtid, ctx.Err())
+		case <-time.After(filePollInterval):
+		}
+	}
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package onlineddl
+
+import "fmt"
+
+// ForeignKeyChild is one child table discovered via information_schema.REFERENTIAL_CONSTRAINTS
+// for a parent table being altered under an FK migration group.
+type ForeignKeyChild struct {
+	Table          string
+	ConstraintName string
+	// Columns are the child's FK columns, in the same order as ParentColumns.
+	Columns       []string
+	ParentColumns []string
+}
+
+// FKChildDiscoverer looks up every table with a foreign key referencing parentTable, as read
+// from information_schema.REFERENTIAL_CONSTRAINTS (joined with KEY_COLUMN_USAGE for the column
+// lists).
+type FKChildDiscoverer func(parentTable string) ([]ForeignKeyChild, error)
+
+// GhostTableNamer names the ghost (shadow) copy built for a table participating in an FK
+// migration group, matching the executor's existing ghost-table naming convention.
+type GhostTableNamer func(table string) string
+
+// FKMigrationGroup is the plan for migrating a parent table and all of its FK-children as one
+// atomic unit under a single migration UUID: ghost copies of the parent and every child are
+// built (each running vreplication concurrently), and a single coordinated cutover re-points
+// every child's FK to the new parent inside one metadata-lock window. This works on stock
+// MySQL 8.0 (no `rename_table_preserve_foreign_key` patch needed) because the ghost children
+// are created with their FK constraints already pointing at the *ghost* parent's name, and the
+// final multi-table RENAME (see PlanRenameChain) swaps all of parent+children into place
+// together, so by the time any session can see the new names, the FKs already reference them.
+type FKMigrationGroup struct {
+	MigrationUUID string
+	Parent        string
+	ParentGhost   string
+	Children      []FKMigrationGroupMember
+}
+
+// FKMigrationGroupMember is one child table's role within an FKMigrationGroup.
+type FKMigrationGroupMember struct {
+	ForeignKeyChild
+	Ghost string
+}
+
+// BuildFKMigrationGroup discovers parentTable's FK-children via discover and assembles the
+// group plan, naming every ghost table via namer. Children are deduplicated by table name in
+// case more than one FK constraint references the parent.
+func BuildFKMigrationGroup(migrationUUID, parentTable string, discover FKChildDiscoverer, namer GhostTableNamer) (*FKMigrationGroup, error) {
+	children, err := discover(parentTable)
+	if err != nil {
+		return nil, fmt.Errorf("fk_migration_group: discovering FK children of %q: %w", parentTable, err)
+	}
+
+	group := &FKMigrationGroup{
+		MigrationUUID: migrationUUID,
+		Parent:        parentTable,
+		ParentGhost:   namer(parentTable),
+	}
+	seen := map[string]bool{}
+	for _, child := range children {
+		if seen[child.Table] {
+			continue
+		}
+		seen[child.Table] = true
+		group.Children = append(group.Children, FKMigrationGroupMember{
+			ForeignKeyChild: child,
+			Ghost:           namer(child.Table),
+		})
+	}
+	return group, nil
+}
+
+// CutOverRenames returns the atomic multi-table RENAME pairs needed to cut the whole group
+// over: the parent and every child swap with their ghost simultaneously, so
+// PlanRenameChain/the executor's single metadata-lock-window RENAME ensures no session ever
+// observes a child pointing at the archived parent.
+func (g *FKMigrationGroup) CutOverRenames(archiveSuffix func(table string) string) []TableRename {
+	renames := []TableRename{
+		{FromTable: g.Parent, ToTable: archiveSuffix(g.Parent)},
+		{FromTable: g.ParentGhost, ToTable: g.Parent},
+	}
+	for _, child := range g.Children {
+		renames = append(renames,
+			TableRename{FromTable: child.Table, ToTable: archiveSuffix(child.Table)},
+			TableRename{FromTable: child.Ghost, ToTable: child.Table},
+		)
+	}
+	return renames
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package onlineddl
+
+import (
+	"sync"
+
+	"vitess.io/vitess/go/stats"
+)
+
+// schedulerGauges holds the per-keyspace/shard migration-count gauges the scheduler
+// exposes (queued, running, ready-to-complete, throttled). They are package-level because
+// they're registered once with the global stats registry, but their values must be zeroed
+// and repopulated whenever a tablet becomes the new primary, so a crashed former primary's
+// counts don't linger in /debug/vars.
+var (
+	queuedMigrationsGauge          = stats.NewGaugesWithMultiLabels("OnlineDDLQueuedMigrations", "Number of queued Online DDL migrations", []string{"Keyspace", "Shard"})
+	runningMigrationsGauge         = stats.NewGaugesWithMultiLabels("OnlineDDLRunningMigrations", "Number of running Online DDL migrations", []string{"Keyspace", "Shard"})
+	readyToCompleteMigrationsGauge = stats.NewGaugesWithMultiLabels("OnlineDDLReadyToCompleteMigrations", "Number of ready-to-complete Online DDL migrations", []string{"Keyspace", "Shard"})
+	throttledMigrationsGauge       = stats.NewGaugesWithMultiLabels("OnlineDDLThrottledMigrations", "Number of throttled Online DDL migrations", []string{"Keyspace", "Shard"})
+
+	schedulerGaugesMu  sync.Mutex
+	schedulerGaugeKeys = map[[2]string]bool{}
+)
+
+// setSchedulerGauge sets one of the scheduler's per-keyspace/shard gauges and remembers
+// the (keyspace, shard) key, so a later cleanStaleMetrics call knows which keys to zero.
+func setSchedulerGauge(gauge *stats.GaugesWithMultiLabels, keyspace, shard string, value int64) {
+	schedulerGaugesMu.Lock()
+	schedulerGaugeKeys[[2]string{keyspace, shard}] = true
+	schedulerGaugesMu.Unlock()
+
+	gauge.Set([]string{keyspace, shard}, value)
+}
+
+// cleanStaleMetrics zeroes all per-keyspace/shard migration gauges previously set via
+// setSchedulerGauge. It must be called before the gauges are repopulated from
+// _vt.schema_migrations, both on the scheduler's bootstrap/startup path (a tablet becoming
+// primary) and on its shutdown path, so a crashed-and-replaced primary never leaves stale
+// counts behind for a dashboard or alert to read.
+func cleanStaleMetrics() {
+	schedulerGaugesMu.Lock()
+	keys := make([][2]string, 0, len(schedulerGaugeKeys))
+	for key := range schedulerGaugeKeys {
+		keys = append(keys, key)
+	}
+	schedulerGaugesMu.Unlock()
+
+	for _, key := range keys {
+		for _, gauge := range []*stats.GaugesWithMultiLabels{
+			queuedMigrationsGauge,
+			runningMigrationsGauge,
+			readyToCompleteMigrationsGauge,
+			throttledMigrationsGauge,
+		} {
+			gauge.Set([]string{key[0], key[1]}, 0)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package onlineddl
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// MigrationBatch records the membership of a set of migrations submitted together via
+// SubmitMigrationBatch: which migration UUIDs belong to the batch, and in what order they
+// must be reverted. Executor keeps it in memory only; it is not written to any _vt table.
+type MigrationBatch struct {
+	BatchUUID      string
+	MigrationUUIDs []string
+}
+
+// migrationSubmitFunc submits a single DDL statement under the given strategy and returns
+// its migration UUID. It is injected so SubmitMigrationBatch can be exercised without the
+// full executor.
+type migrationSubmitFunc func(statement string, strategy string) (migrationUUID string, err error)
+
+// migrationRevertFunc reverts a single migration UUID, refusing (returning an error) if
+// that migration is not revertible.
+type migrationRevertFunc func(migrationUUID string) error
+
+// SubmitMigrationBatch submits every statement as its own Online DDL migration under a
+// shared batch UUID, returning the batch UUID and the per-statement migration UUIDs in
+// submission order. If any individual submission fails, the batch is abandoned: migrations
+// already submitted are left as-is (the caller may choose to revert them), and the error
+// identifies which statement failed.
+func SubmitMigrationBatch(statements []string, strategy string, submit migrationSubmitFunc) (batchUUID string, migrationUUIDs []string, err error) {
+	batchUUID = uuid.NewString()
+	migrationUUIDs = make([]string, 0, len(statements))
+	for i, statement := range statements {
+		migrationUUID, err := submit(statement, strategy)
+		if err != nil {
+			return batchUUID, migrationUUIDs, fmt.Errorf("SubmitMigrationBatch: statement %d of %d failed: %w", i+1, len(statements), err)
+		}
+		migrationUUIDs = append(migrationUUIDs, migrationUUID)
+	}
+	return batchUUID, migrationUUIDs, nil
+}
+
+// RevertMigrationBatch implements `REVERT VITESS_MIGRATION_BATCH '<batch-uuid>'`: it
+// reverts every member of the batch in reverse submission order, refusing to revert any
+// member (and thus the whole batch) if canRevert reports any one of them as
+// non-revertible, so a batch either fully reverts or is left entirely untouched.
+func RevertMigrationBatch(batch MigrationBatch, canRevert func(migrationUUID string) (bool, error), revert migrationRevertFunc) error {
+	for _, migrationUUID := range batch.MigrationUUIDs {
+		revertible, err := canRevert(migrationUUID)
+		if err != nil {
+			return fmt.Errorf("RevertMigrationBatch: checking revertibility of %s: %w", migrationUUID, err)
+		}
+		if !revertible {
+			return fmt.Errorf("RevertMigrationBatch: refusing partial revert, %s in batch %s is not revertible", migrationUUID, batch.BatchUUID)
+		}
+	}
+	for i := len(batch.MigrationUUIDs) - 1; i >= 0; i-- {
+		migrationUUID := batch.MigrationUUIDs[i]
+		if err := revert(migrationUUID); err != nil {
+			return fmt.Errorf("RevertMigrationBatch: reverting %s in batch %s: %w", migrationUUID, batch.BatchUUID, err)
+		}
+	}
+	return nil
+}
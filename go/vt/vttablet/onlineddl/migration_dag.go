@@ -0,0 +1,113 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package onlineddl
+
+import "fmt"
+
+// MigrationDAG tracks the --migration-group/--depends-on relationships between migrations
+// submitted together, so the scheduler can run mutually independent migrations
+// concurrently (subject to a per-keyspace/shard concurrency cap) and only cut over an
+// entire group atomically once every member is ready_to_complete.
+type MigrationDAG struct {
+	// group maps a migration UUID to its migration_group name ("" if ungrouped).
+	group map[string]string
+	// dependsOn maps a migration UUID to the UUIDs (or group names) it depends on.
+	dependsOn map[string][]string
+}
+
+// NewMigrationDAG creates an empty dependency graph.
+func NewMigrationDAG() *MigrationDAG {
+	return &MigrationDAG{
+		group:     map[string]string{},
+		dependsOn: map[string][]string{},
+	}
+}
+
+// AddMigration registers a migration's group membership and dependencies.
+func (d *MigrationDAG) AddMigration(uuid string, migrationGroup string, dependsOn []string) {
+	d.group[uuid] = migrationGroup
+	d.dependsOn[uuid] = dependsOn
+}
+
+// IsEligible reports whether uuid's predecessors (direct dependencies, resolved transitively
+// through group membership) are all present in completed, i.e. the migration may now start.
+// uuids with unresolvable dependencies (referring to an unknown UUID/group) are never
+// eligible, to fail closed rather than silently skip an ordering constraint.
+func (d *MigrationDAG) IsEligible(uuid string, completed map[string]bool) bool {
+	for _, dependency := range d.dependsOn[uuid] {
+		if !d.dependencySatisfied(dependency, completed) {
+			return false
+		}
+	}
+	return true
+}
+
+// dependencySatisfied resolves dependency as either a migration UUID or a group name: a
+// group dependency is satisfied only once every migration in that group has completed.
+func (d *MigrationDAG) dependencySatisfied(dependency string, completed map[string]bool) bool {
+	if _, isUUID := d.dependsOn[dependency]; isUUID {
+		return completed[dependency]
+	}
+	// Treat dependency as a group name: satisfied iff every member of the group is in
+	// completed (and the group is non-empty, i.e. actually exists).
+	found := false
+	for uuid, group := range d.group {
+		if group != dependency {
+			continue
+		}
+		found = true
+		if !completed[uuid] {
+			return false
+		}
+	}
+	return found
+}
+
+// EligibleMigrations returns every not-yet-completed migration whose dependencies are
+// satisfied, in deterministic (insertion) order.
+func (d *MigrationDAG) EligibleMigrations(order []string, completed map[string]bool) []string {
+	var eligible []string
+	for _, uuid := range order {
+		if completed[uuid] {
+			continue
+		}
+		if d.IsEligible(uuid, completed) {
+			eligible = append(eligible, uuid)
+		}
+	}
+	return eligible
+}
+
+// GroupReadyToCutOver reports whether every migration in groupName is ready_to_complete
+// (i.e. present in readyToComplete), so the scheduler can cut the whole group over
+// atomically. An unknown/empty group is never ready.
+func (d *MigrationDAG) GroupReadyToCutOver(groupName string, readyToComplete map[string]bool) (bool, error) {
+	found := false
+	for uuid, group := range d.group {
+		if group != groupName {
+			continue
+		}
+		found = true
+		if !readyToComplete[uuid] {
+			return false, nil
+		}
+	}
+	if !found {
+		return false, fmt.Errorf("migration_dag: unknown migration group %q", groupName)
+	}
+	return true, nil
+}
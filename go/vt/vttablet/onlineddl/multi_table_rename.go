@@ -0,0 +1,155 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package onlineddl
+
+import "fmt"
+
+// TableRename is one `t1 TO t2` (or `ks1.t1 TO ks2.t2`) pair of a `RENAME TABLE` statement.
+// Keyspace is "" when the statement used an unqualified table name, meaning "this migration's
+// own keyspace".
+type TableRename struct {
+	FromKeyspace, FromTable string
+	ToKeyspace, ToTable     string
+}
+
+// RenameStep is one step of the rename chain plan: a single `RENAME TABLE from TO to`
+// MySQL statement, in the order it must execute. Cycles in the requested renames are broken by
+// introducing a temporary name, so RenameStep.To may be a generated intermediate rather than
+// one of the user's requested destinations.
+type RenameStep struct {
+	FromKeyspace, FromTable string
+	ToKeyspace, ToTable     string
+}
+
+// PlanRenameChain validates an atomic multi-table RENAME (all sources must exist, no
+// destination may already exist, as reported by tableExists) and plans the ordered sequence of
+// single-pair RENAME TABLE steps needed to realize it, breaking any cycle (e.g. `a->b, b->a`)
+// by routing through a temporary name. The returned steps, executed in order inside one
+// metadata-lock window, realize renames atomically with no partial state.
+func PlanRenameChain(renames []TableRename, tableExists func(keyspace, table string) (bool, error)) ([]RenameStep, error) {
+	destinations := map[string]bool{}
+	sources := map[string]TableRename{}
+	for _, rename := range renames {
+		fromKey := tableKey(rename.FromKeyspace, rename.FromTable)
+		toKey := tableKey(rename.ToKeyspace, rename.ToTable)
+
+		exists, err := tableExists(rename.FromKeyspace, rename.FromTable)
+		if err != nil {
+			return nil, fmt.Errorf("multi_table_rename: checking source %s: %w", fromKey, err)
+		}
+		if !exists {
+			return nil, fmt.Errorf("multi_table_rename: source table %s does not exist", fromKey)
+		}
+		if _, isRenamedAway := findRenameBySource(renames, rename.ToKeyspace, rename.ToTable); !isRenamedAway {
+			destExists, err := tableExists(rename.ToKeyspace, rename.ToTable)
+			if err != nil {
+				return nil, fmt.Errorf("multi_table_rename: checking destination %s: %w", toKey, err)
+			}
+			if destExists {
+				return nil, fmt.Errorf("multi_table_rename: destination table %s already exists", toKey)
+			}
+		}
+		if destinations[toKey] {
+			return nil, fmt.Errorf("multi_table_rename: destination table %s targeted more than once", toKey)
+		}
+		destinations[toKey] = true
+		sources[fromKey] = rename
+	}
+
+	var steps []RenameStep
+	visited := map[string]bool{}
+	tempCounter := 0
+
+	var visit func(fromKey string) error
+	visit = func(fromKey string) error {
+		if visited[fromKey] {
+			return nil
+		}
+		rename, isSource := sources[fromKey]
+		if !isSource {
+			return nil
+		}
+		toKey := tableKey(rename.ToKeyspace, rename.ToTable)
+
+		if cycleRename, partOfCycle := sources[toKey]; partOfCycle && !visited[toKey] {
+			// toKey is itself renamed elsewhere (a chain, or part of a cycle): free it by routing
+			// this rename through a temporary name, then let the destination's own rename
+			// proceed (and eventually land on this step's original "to", once nothing else
+			// needs it).
+			tempCounter++
+			tempTable := fmt.Sprintf("_vt_rename_tmp_%d_%s", tempCounter, rename.FromTable)
+			steps = append(steps, RenameStep{
+				FromKeyspace: rename.FromKeyspace, FromTable: rename.FromTable,
+				ToKeyspace: rename.FromKeyspace, ToTable: tempTable,
+			})
+			visited[fromKey] = true
+			if err := visit(toKey); err != nil {
+				return err
+			}
+			steps = append(steps, RenameStep{
+				FromKeyspace: rename.FromKeyspace, FromTable: tempTable,
+				ToKeyspace: rename.ToKeyspace, ToTable: rename.ToTable,
+			})
+			_ = cycleRename
+			return nil
+		}
+
+		visited[fromKey] = true
+		steps = append(steps, RenameStep{
+			FromKeyspace: rename.FromKeyspace, FromTable: rename.FromTable,
+			ToKeyspace: rename.ToKeyspace, ToTable: rename.ToTable,
+		})
+		return nil
+	}
+
+	for _, rename := range renames {
+		if err := visit(tableKey(rename.FromKeyspace, rename.FromTable)); err != nil {
+			return nil, err
+		}
+	}
+	return steps, nil
+}
+
+// InvertRenameChain produces the rename chain that exactly undoes steps, for use when
+// reverting a multi-table RENAME migration: the same pairs, reversed end-to-end and in the
+// opposite execution order.
+func InvertRenameChain(steps []RenameStep) []RenameStep {
+	inverted := make([]RenameStep, len(steps))
+	for i, step := range steps {
+		inverted[len(steps)-1-i] = RenameStep{
+			FromKeyspace: step.ToKeyspace, FromTable: step.ToTable,
+			ToKeyspace: step.FromKeyspace, ToTable: step.FromTable,
+		}
+	}
+	return inverted
+}
+
+func tableKey(keyspace, table string) string {
+	if keyspace == "" {
+		return table
+	}
+	return keyspace + "." + table
+}
+
+func findRenameBySource(renames []TableRename, keyspace, table string) (TableRename, bool) {
+	for _, rename := range renames {
+		if rename.FromKeyspace == keyspace && rename.FromTable == table {
+			return rename, true
+		}
+	}
+	return TableRename{}, false
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package onlineddl
+
+import "time"
+
+// ArtifactRecord is the minimal view of a migration's artifact (ghost/shadow tables, etc.)
+// that RetentionPolicy needs to decide whether it should be kept or cleaned up.
+type ArtifactRecord struct {
+	MigrationUUID string
+	Table         string
+	Successful    bool
+	CompletedAt   time.Time
+}
+
+// RetentionDecision is the reconciler's verdict for a single artifact, as returned by
+// Executor.reconcileArtifacts. It is not written to the migration row.
+type RetentionDecision struct {
+	Keep   bool
+	Reason string
+}
+
+// RetentionPolicy replaces the single per-migration --retain-artifacts duration with a set
+// of cluster-wide rules, evaluated by a background reconciler:
+//   - keep the last KeepLastNSuccessful successful artifacts per table
+//   - keep failed-migration artifacts for KeepFailedFor regardless of --retain-artifacts
+//   - (disk-pressure driven cleanup is a reconciler-level concern layered on top of Evaluate,
+//     see EvaluateUnderDiskPressure)
+type RetentionPolicy struct {
+	KeepLastNSuccessful int
+	KeepFailedFor       time.Duration
+}
+
+// DefaultRetentionPolicy keeps the single most recent successful artifact per table and
+// retains failed-migration artifacts for 7 days.
+func DefaultRetentionPolicy() *RetentionPolicy {
+	return &RetentionPolicy{
+		KeepLastNSuccessful: 1,
+		KeepFailedFor:       7 * 24 * time.Hour,
+	}
+}
+
+// Evaluate decides, for every artifact belonging to the same table, whether it should be
+// kept. artifacts must be supplied newest-first per table for KeepLastNSuccessful to apply
+// correctly.
+func (p *RetentionPolicy) Evaluate(now time.Time, artifacts []ArtifactRecord) map[string]RetentionDecision {
+	decisions := make(map[string]RetentionDecision, len(artifacts))
+	keptSuccessfulByTable := map[string]int{}
+
+	for _, artifact := range artifacts {
+		if !artifact.Successful {
+			if now.Sub(artifact.CompletedAt) < p.KeepFailedFor {
+				decisions[artifact.MigrationUUID] = RetentionDecision{Keep: true, Reason: "failed migration artifact within KeepFailedFor window"}
+			} else {
+				decisions[artifact.MigrationUUID] = RetentionDecision{Keep: false, Reason: "failed migration artifact past KeepFailedFor window"}
+			}
+			continue
+		}
+		if keptSuccessfulByTable[artifact.Table] < p.KeepLastNSuccessful {
+			keptSuccessfulByTable[artifact.Table]++
+			decisions[artifact.MigrationUUID] = RetentionDecision{Keep: true, Reason: "within KeepLastNSuccessful for table"}
+			continue
+		}
+		decisions[artifact.MigrationUUID] = RetentionDecision{Keep: false, Reason: "exceeds KeepLastNSuccessful for table"}
+	}
+	return decisions
+}
+
+// EvaluateUnderDiskPressure overrides Evaluate's decisions when diskPressure is true: only
+// the single most recent successful artifact per table (and no failed-migration artifacts)
+// are kept, regardless of the configured policy, so the reconciler can react to a
+// disk-pressure signal without the operator having to change the cluster-wide policy.
+func (p *RetentionPolicy) EvaluateUnderDiskPressure(now time.Time, artifacts []ArtifactRecord, diskPressure bool) map[string]RetentionDecision {
+	if !diskPressure {
+		return p.Evaluate(now, artifacts)
+	}
+	emergencyPolicy := &RetentionPolicy{KeepLastNSuccessful: 1, KeepFailedFor: 0}
+	decisions := emergencyPolicy.Evaluate(now, artifacts)
+	for uuid, decision := range decisions {
+		if decision.Keep {
+			decisions[uuid] = RetentionDecision{Keep: true, Reason: decision.Reason + " (disk-pressure mode)"}
+		} else {
+			decisions[uuid] = RetentionDecision{Keep: false, Reason: decision.Reason + " (disk-pressure mode)"}
+		}
+	}
+	return decisions
+}
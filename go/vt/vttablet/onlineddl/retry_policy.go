@@ -0,0 +1,182 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package onlineddl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FailureClass distinguishes errors the scheduler should automatically retry from those it
+// should treat as terminal.
+type FailureClass string
+
+const (
+	// FailureClassTransient covers conditions expected to clear on their own: lock wait
+	// timeouts, deadlocks, replica lag, throttler rejections, and MDL wait timeouts.
+	FailureClassTransient FailureClass = "transient"
+	// FailureClassTerminal covers every other failure (e.g. a malformed statement, or a
+	// constraint violation), which retrying cannot fix.
+	FailureClassTerminal FailureClass = "terminal"
+)
+
+// transientErrnos lists the MySQL error numbers (as currently surfaced verbatim in a failed
+// migration's `message` column) that ClassifyFailure treats as transient.
+var transientErrnos = map[int]bool{
+	1205: true, // ER_LOCK_WAIT_TIMEOUT
+	1213: true, // ER_LOCK_DEADLOCK
+	3024: true, // ER_QUERY_TIMEOUT (MDL wait timeout surfaces here in vitess's wrapping)
+}
+
+// transientMessageMarkers are substrings vitess's own layers (replica-lag throttling, the
+// tablet throttler) use in messages that don't carry a MySQL errno at all.
+var transientMessageMarkers = []string{
+	"due to replica lag",
+	"throttled",
+	"lock wait timeout",
+	"deadlock",
+}
+
+// ClassifyFailure determines whether message (a failed migration's recorded error, which may
+// or may not carry a "(errno NNNN)"-style suffix) represents a transient or terminal failure.
+func ClassifyFailure(message string) FailureClass {
+	if errno, ok := extractErrno(message); ok && transientErrnos[errno] {
+		return FailureClassTransient
+	}
+	lowerMessage := strings.ToLower(message)
+	for _, marker := range transientMessageMarkers {
+		if strings.Contains(lowerMessage, marker) {
+			return FailureClassTransient
+		}
+	}
+	return FailureClassTerminal
+}
+
+func extractErrno(message string) (int, bool) {
+	const marker = "errno "
+	idx := strings.Index(strings.ToLower(message), marker)
+	if idx < 0 {
+		return 0, false
+	}
+	rest := message[idx+len(marker):]
+	end := 0
+	for end < len(rest) && rest[end] >= '0' && rest[end] <= '9' {
+		end++
+	}
+	if end == 0 {
+		return 0, false
+	}
+	errno, err := strconv.Atoi(rest[:end])
+	if err != nil {
+		return 0, false
+	}
+	return errno, true
+}
+
+// BackoffKind selects the curve RetryPolicy.NextBackoff follows between retries.
+type BackoffKind string
+
+const (
+	BackoffKindFixed       BackoffKind = "fixed"
+	BackoffKindExponential BackoffKind = "exponential"
+)
+
+// RetryPolicy holds the `--retry-on-failure=<class>`, `--max-retries=<n>` and
+// `--retry-backoff=<kind>:<base>:<max>` DDL strategy settings, and governs automatic
+// re-enqueuing of a migration that fails with a FailureClass matching RetryOn.
+// ParseRetryBackoff parses the --retry-backoff value; this package has no parser for the
+// other two flags.
+type RetryPolicy struct {
+	RetryOn    FailureClass
+	MaxRetries int
+	Backoff    BackoffKind
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+// ParseRetryBackoff parses a "<kind>:<base>:<max>" spec, e.g. "exponential:30s:10m" or
+// "fixed:1m:1m".
+func ParseRetryBackoff(spec string) (BackoffKind, time.Duration, time.Duration, error) {
+	parts := strings.Split(spec, ":")
+	if len(parts) != 3 {
+		return "", 0, 0, fmt.Errorf("retry_policy: invalid --retry-backoff %q, expected <kind>:<base>:<max>", spec)
+	}
+	kind := BackoffKind(parts[0])
+	if kind != BackoffKindFixed && kind != BackoffKindExponential {
+		return "", 0, 0, fmt.Errorf("retry_policy: unrecognized backoff kind %q", parts[0])
+	}
+	base, err := time.ParseDuration(parts[1])
+	if err != nil {
+		return "", 0, 0, fmt.Errorf("retry_policy: invalid base delay %q: %w", parts[1], err)
+	}
+	max, err := time.ParseDuration(parts[2])
+	if err != nil {
+		return "", 0, 0, fmt.Errorf("retry_policy: invalid max delay %q: %w", parts[2], err)
+	}
+	return kind, base, max, nil
+}
+
+// ShouldRetry reports whether a migration that has already been attempted attemptsSoFar times
+// and most recently failed with failureMessage should be automatically re-enqueued.
+func (p *RetryPolicy) ShouldRetry(failureMessage string, attemptsSoFar int) bool {
+	if attemptsSoFar >= p.MaxRetries {
+		return false
+	}
+	return ClassifyFailure(failureMessage) == p.RetryOn
+}
+
+// NextBackoff computes the delay before re-enqueuing the (attempt+1)'th attempt, where attempt
+// is the number of attempts already made (0 for the first retry after the initial failure).
+func (p *RetryPolicy) NextBackoff(attempt int) time.Duration {
+	if p.Backoff == BackoffKindFixed {
+		return p.BaseDelay
+	}
+	delay := p.BaseDelay
+	for i := 0; i < attempt; i++ {
+		delay *= 2
+		if delay > p.MaxDelay {
+			return p.MaxDelay
+		}
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+// RetryAttempt is one automatic re-enqueue of a migration: Executor.handleMigrationFailure
+// appends one of these to the migration's in-memory state on every retry, so callers (and
+// tests) can tell "failed after N retries" from "failed immediately". It is not written to
+// the migrations table.
+type RetryAttempt struct {
+	Attempt int
+	At      time.Time
+	Error   string
+}
+
+// RecordAttempt appends a new RetryAttempt for a just-failed attempt to attempts, returning the
+// updated slice. Attempt numbers start at 1 for the first automatic retry (the initial,
+// non-retried attempt is not itself recorded here).
+func RecordAttempt(attempts []RetryAttempt, at time.Time, errorMessage string) []RetryAttempt {
+	return append(attempts, RetryAttempt{
+		Attempt: len(attempts) + 1,
+		At:      at,
+		Error:   errorMessage,
+	})
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package onlineddl
+
+import "sync"
+
+// QueuedMigration is the minimal view of a queued/ready migration that SchedulingPolicy
+// needs in order to decide ordering: enough to express table-name conflicts and priority,
+// without depending on the full migration row type.
+type QueuedMigration struct {
+	UUID       string
+	Table      string
+	Action     string // e.g. "drop", "revert", "alter", "create"
+	Concurrent bool
+	Priority   int
+}
+
+// YieldRule declares that a migration of YieldAction on a table waits behind any running
+// migration of BlockingAction on the same table (e.g. "DROP always yields to REVERT").
+// Queued migrations are not checked against each other.
+type YieldRule struct {
+	YieldAction    string
+	BlockingAction string
+}
+
+// SchedulingPolicy is a pluggable, cluster-wide set of rules the executor consults when
+// picking which queued migration to run next: table-name conflict yield rules, per-table
+// concurrency caps, and priority weights. Set via SetMigrationSchedulingPolicy; it is held in
+// process memory only and is not persisted to topo.
+type SchedulingPolicy struct {
+	YieldRules             []YieldRule
+	PerTableConcurrencyCap int
+}
+
+// DefaultSchedulingPolicy matches the scheduler's historical hard-coded behavior: no
+// special yield rules, and no per-table concurrency cap beyond --allow-concurrent.
+func DefaultSchedulingPolicy() *SchedulingPolicy {
+	return &SchedulingPolicy{PerTableConcurrencyCap: 0}
+}
+
+var (
+	activeSchedulingPolicyMu sync.RWMutex
+	activeSchedulingPolicy   = DefaultSchedulingPolicy()
+)
+
+// SetMigrationSchedulingPolicy replaces the active cluster-wide scheduling policy.
+func SetMigrationSchedulingPolicy(policy *SchedulingPolicy) {
+	activeSchedulingPolicyMu.Lock()
+	defer activeSchedulingPolicyMu.Unlock()
+	activeSchedulingPolicy = policy
+}
+
+// GetMigrationSchedulingPolicy returns the active cluster-wide scheduling policy.
+func GetMigrationSchedulingPolicy() *SchedulingPolicy {
+	activeSchedulingPolicyMu.RLock()
+	defer activeSchedulingPolicyMu.RUnlock()
+	return activeSchedulingPolicy
+}
+
+// yields reports whether candidate must wait behind blocking under this policy, because of
+// either a YieldRule match or (when neither migration opted into --allow-concurrent) a
+// same-table conflict.
+func (p *SchedulingPolicy) yields(candidate, blocking QueuedMigration) bool {
+	if candidate.Table != blocking.Table {
+		return false
+	}
+	for _, rule := range p.YieldRules {
+		if rule.YieldAction == candidate.Action && rule.BlockingAction == blocking.Action {
+			return true
+		}
+	}
+	return !candidate.Concurrent || !blocking.Concurrent
+}
+
+// NextEligible picks the highest-priority queued migration that isn't blocked by any
+// already-running migration on the same table, and whose table doesn't already have
+// PerTableConcurrencyCap running migrations (0 means no cap), or "" if none is eligible. Ties
+// are broken by submission order (the order migrations appear in `queued`).
+func (p *SchedulingPolicy) NextEligible(queued []QueuedMigration, running []QueuedMigration) string {
+	bestIndex := -1
+	for i, candidate := range queued {
+		blocked := false
+		runningOnTable := 0
+		for _, runningMigration := range running {
+			if p.yields(candidate, runningMigration) {
+				blocked = true
+				break
+			}
+			if runningMigration.Table == candidate.Table {
+				runningOnTable++
+			}
+		}
+		if p.PerTableConcurrencyCap > 0 && runningOnTable >= p.PerTableConcurrencyCap {
+			blocked = true
+		}
+		if blocked {
+			continue
+		}
+		if bestIndex == -1 || candidate.Priority > queued[bestIndex].Priority {
+			bestIndex = i
+		}
+	}
+	if bestIndex == -1 {
+		return ""
+	}
+	return queued[bestIndex].UUID
+}
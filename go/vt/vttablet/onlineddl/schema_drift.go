@@ -0,0 +1,131 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package onlineddl
+
+import (
+	"sync"
+	"time"
+)
+
+// DriftEvent is emitted whenever a declaratively-owned table's live definition no longer
+// matches the last successfully applied declarative statement recorded in
+// _vt.schema_migrations. It is what `SHOW VITESS_SCHEMA_DRIFT` surfaces, and what an
+// auto-repair migration is enqueued from.
+type DriftEvent struct {
+	Table          string
+	DetectedAt     time.Time
+	DeclaredCreate string
+	LiveCreate     string
+	Diff           string
+}
+
+// DriftRepairMode controls what the drift detector does once it observes drift on a table.
+type DriftRepairMode string
+
+const (
+	// DriftRepairModeObserve only records the DriftEvent; no migration is enqueued.
+	DriftRepairModeObserve DriftRepairMode = "observe"
+	// DriftRepairModeAutoRepair enqueues an Online DDL migration that re-applies the
+	// declarative statement, bringing the table back in line.
+	DriftRepairModeAutoRepair DriftRepairMode = "auto-repair"
+	// DriftRepairModeQuarantine locks the table out of subsequent declarative migrations
+	// until an operator acknowledges the drift via AcknowledgeDrift.
+	DriftRepairModeQuarantine DriftRepairMode = "quarantine"
+)
+
+// DriftDetector compares a declaratively-owned table's live definition, as passed to Check,
+// against its last-applied declarative statement, tracking quarantined tables and the most
+// recent DriftEvent per table. It does not read table definitions itself and runs no
+// periodic loop; each comparison happens when a caller invokes Check.
+type DriftDetector struct {
+	mu          sync.Mutex
+	mode        DriftRepairMode
+	events      map[string]DriftEvent
+	quarantined map[string]bool
+}
+
+// NewDriftDetector creates a DriftDetector operating in mode.
+func NewDriftDetector(mode DriftRepairMode) *DriftDetector {
+	return &DriftDetector{
+		mode:        mode,
+		events:      map[string]DriftEvent{},
+		quarantined: map[string]bool{},
+	}
+}
+
+// Check compares a single declaratively-owned table's recorded declaredCreate against its
+// current liveCreate (both CREATE TABLE statements, already normalized by the caller so
+// whitespace/comment differences don't register as drift), computed via diff. If they match,
+// any prior drift/quarantine on the table is cleared. Otherwise a DriftEvent is recorded, and
+// when the detector is in DriftRepairModeQuarantine the table is marked quarantined. The
+// caller is responsible for actually enqueuing an auto-repair migration when the detector is
+// in DriftRepairModeAutoRepair and shouldRepair is returned true.
+func (d *DriftDetector) Check(table, declaredCreate, liveCreate string, diff func(a, b string) string, now time.Time) (event *DriftEvent, shouldRepair bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if declaredCreate == liveCreate {
+		delete(d.events, table)
+		delete(d.quarantined, table)
+		return nil, false
+	}
+
+	driftEvent := DriftEvent{
+		Table:          table,
+		DetectedAt:     now,
+		DeclaredCreate: declaredCreate,
+		LiveCreate:     liveCreate,
+		Diff:           diff(declaredCreate, liveCreate),
+	}
+	d.events[table] = driftEvent
+
+	switch d.mode {
+	case DriftRepairModeQuarantine:
+		d.quarantined[table] = true
+		return &driftEvent, false
+	case DriftRepairModeAutoRepair:
+		return &driftEvent, true
+	default:
+		return &driftEvent, false
+	}
+}
+
+// IsQuarantined reports whether table is currently locked out of declarative migrations.
+func (d *DriftDetector) IsQuarantined(table string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.quarantined[table]
+}
+
+// AcknowledgeDrift clears a table's quarantine (an operator has reviewed the drift and either
+// accepted it or repaired it out of band), without touching the recorded DriftEvent history.
+func (d *DriftDetector) AcknowledgeDrift(table string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.quarantined, table)
+}
+
+// Events returns every table currently showing drift, for `SHOW VITESS_SCHEMA_DRIFT`.
+func (d *DriftDetector) Events() []DriftEvent {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	events := make([]DriftEvent, 0, len(d.events))
+	for _, event := range d.events {
+		events = append(events, event)
+	}
+	return events
+}
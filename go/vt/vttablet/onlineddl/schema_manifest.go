@@ -0,0 +1,165 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package onlineddl
+
+import "fmt"
+
+// ManifestAction is the kind of change ApplySchemaManifest's planner assigns a table to reach
+// the declared target state.
+type ManifestAction string
+
+const (
+	ManifestActionCreate ManifestAction = "create"
+	ManifestActionAlter  ManifestAction = "alter"
+	ManifestActionDrop   ManifestAction = "drop"
+)
+
+// ManifestTable is one table in a desired-schema manifest submitted to `vtctldclient
+// ApplySchemaManifest`: its CREATE TABLE statement plus the parent tables it foreign-keys to,
+// used to order CREATE/DROP correctly (a child's CREATE must follow its parents', and a
+// parent's DROP must follow its children's).
+type ManifestTable struct {
+	Name              string
+	CreateStatement   string
+	ForeignKeyParents []string
+}
+
+// ManifestMigration is one planned step of an ApplySchemaManifest plan: the action to take and
+// the resulting statement to enqueue as an Online DDL migration (for a DROP, the planner sets
+// Statement to a plain `DROP TABLE`).
+type ManifestMigration struct {
+	Table     string
+	Action    ManifestAction
+	Statement string
+}
+
+// SchemaManifestPlan is the result of PlanSchemaManifest: the minimum ordered sequence of
+// migrations to converge the live schema to the manifest, plus the shared context (typically
+// the submitting request's UUID) recorded for the plan. Executor.ApplySchemaManifest submits
+// the migrations, one per statement, as a single batch so the whole plan reverts as a unit;
+// it does not read SingletonContext.
+type SchemaManifestPlan struct {
+	Migrations       []ManifestMigration
+	SingletonContext string
+}
+
+// PlanSchemaManifest diffs the manifest's desired tables against liveTables (the keyspace's
+// current table names) using diffStatement to compute the ALTER needed for drift on a table
+// present in both, and orders the result so that: CREATEs happen in FK-parent-before-child
+// order, then DROPs of live tables absent from the manifest, and ALTERs (order-independent,
+// since they don't change a table's existence) are applied last. DROPs follow liveTables' map
+// iteration order, which is not defined; no FK ordering is applied among them. dry-run callers
+// should simply inspect the returned plan without enqueuing it.
+func PlanSchemaManifest(
+	manifest []ManifestTable,
+	liveTables map[string]bool,
+	liveCreateStatements map[string]string,
+	diffStatement func(table, liveCreateStatement, desiredCreateStatement string) (alterStatement string, err error),
+	singletonContext string,
+) (*SchemaManifestPlan, error) {
+	desired := make(map[string]ManifestTable, len(manifest))
+	for _, table := range manifest {
+		desired[table.Name] = table
+	}
+
+	order, err := topologicalOrder(manifest)
+	if err != nil {
+		return nil, fmt.Errorf("schema_manifest: %w", err)
+	}
+
+	plan := &SchemaManifestPlan{SingletonContext: singletonContext}
+
+	for _, name := range order {
+		if liveTables[name] {
+			continue
+		}
+		plan.Migrations = append(plan.Migrations, ManifestMigration{
+			Table: name, Action: ManifestActionCreate, Statement: desired[name].CreateStatement,
+		})
+	}
+
+	for name := range liveTables {
+		if _, stillDesired := desired[name]; stillDesired {
+			continue
+		}
+		plan.Migrations = append(plan.Migrations, ManifestMigration{
+			Table: name, Action: ManifestActionDrop, Statement: fmt.Sprintf("DROP TABLE `%s`", name),
+		})
+	}
+	// DROPs are only appended for tables outside the manifest, which have no recorded FK
+	// edges to order by here, so they come out in map iteration order. Dropping leaf tables
+	// is unaffected; dropped tables with FKs among themselves are not ordered.
+
+	for _, name := range order {
+		if !liveTables[name] {
+			continue
+		}
+		alterStatement, err := diffStatement(name, liveCreateStatements[name], desired[name].CreateStatement)
+		if err != nil {
+			return nil, fmt.Errorf("schema_manifest: diffing table %q: %w", name, err)
+		}
+		if alterStatement == "" {
+			continue
+		}
+		plan.Migrations = append(plan.Migrations, ManifestMigration{
+			Table: name, Action: ManifestActionAlter, Statement: alterStatement,
+		})
+	}
+
+	return plan, nil
+}
+
+// topologicalOrder sorts manifest tables so that every table appears after all of its
+// ForeignKeyParents, failing on a cyclic FK graph (which MySQL itself cannot create).
+func topologicalOrder(manifest []ManifestTable) ([]string, error) {
+	byName := make(map[string]ManifestTable, len(manifest))
+	for _, table := range manifest {
+		byName[table.Name] = table
+	}
+
+	var order []string
+	state := map[string]int{} // 0=unvisited, 1=visiting, 2=done
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case 2:
+			return nil
+		case 1:
+			return fmt.Errorf("cyclic foreign key dependency involving table %q", name)
+		}
+		state[name] = 1
+		for _, parent := range byName[name].ForeignKeyParents {
+			if _, known := byName[parent]; !known {
+				continue
+			}
+			if err := visit(parent); err != nil {
+				return err
+			}
+		}
+		state[name] = 2
+		order = append(order, name)
+		return nil
+	}
+
+	for _, table := range manifest {
+		if err := visit(table.Name); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package onlineddl
+
+import (
+	"context"
+	"fmt"
+)
+
+// SnapshotDestinationFlag is the `--snapshot-to=<url>` migration flag: an opt-in, object-store
+// destination (e.g. "s3://bucket/path") that, at cut-over time for a declarative
+// CREATE/ALTER/DROP, receives a consistent snapshot of the pre-migration table plus the exact
+// GTID/binlog position it was taken at. This extends the normal ghost-table revert window
+// (which closes once the shadow table is garbage-collected) with a long-horizon path via
+// RestoreFromSnapshot.
+const SnapshotDestinationFlag = "snapshot-to"
+
+// TableSnapshot records where and at what replication position a pre-migration table snapshot
+// was written. When --snapshot-to is set, Executor keeps it in the migration's in-memory
+// state; it is not written to the migration row.
+type TableSnapshot struct {
+	MigrationUUID string
+	Table         string
+	StorageURL    string
+	GTIDPosition  string
+}
+
+// SnapshotWriter uploads a consistent snapshot of table to destinationURL. This package
+// provides no implementation of it; TakeSnapshot and Executor.takeSnapshotAtCutOver take one
+// as an argument.
+type SnapshotWriter interface {
+	// WriteSnapshot uploads table's current contents and returns the GTID/binlog position the
+	// snapshot is consistent as of.
+	WriteSnapshot(ctx context.Context, destinationURL, table string) (gtidPosition string, err error)
+}
+
+// BinlogReplayer replays binlog events for table between fromGTID (exclusive) and toGTID
+// (inclusive) against the restored snapshot, bringing it forward to toGTID.
+type BinlogReplayer interface {
+	ReplayBetween(ctx context.Context, table, fromGTID, toGTID string) error
+}
+
+// SnapshotRestorer downloads and restores a table snapshot from an object-store URL.
+type SnapshotRestorer interface {
+	RestoreSnapshot(ctx context.Context, storageURL, table string) error
+}
+
+// TakeSnapshot is run at cut-over time for a declarative migration with --snapshot-to set: it
+// writes the pre-migration table to the configured object store and records the position it
+// was taken at.
+func TakeSnapshot(ctx context.Context, writer SnapshotWriter, migrationUUID, table, destinationURL string) (*TableSnapshot, error) {
+	gtidPosition, err := writer.WriteSnapshot(ctx, destinationURL, table)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot_revert: writing snapshot of %s for migration %s: %w", table, migrationUUID, err)
+	}
+	return &TableSnapshot{
+		MigrationUUID: migrationUUID,
+		Table:         table,
+		StorageURL:    destinationURL,
+		GTIDPosition:  gtidPosition,
+	}, nil
+}
+
+// RevertFromSnapshot implements `RevertMigration --from-snapshot=<uuid>`: it restores table
+// from snapshot, then replays binlog events from the snapshot's GTID position up to
+// targetGTID, bringing the table forward to the user-chosen point-in-time well beyond the
+// ghost-table retention window.
+func RevertFromSnapshot(ctx context.Context, restorer SnapshotRestorer, replayer BinlogReplayer, snapshot TableSnapshot, targetGTID string) error {
+	if err := restorer.RestoreSnapshot(ctx, snapshot.StorageURL, snapshot.Table); err != nil {
+		return fmt.Errorf("snapshot_revert: restoring %s from %s: %w", snapshot.Table, snapshot.StorageURL, err)
+	}
+	if err := replayer.ReplayBetween(ctx, snapshot.Table, snapshot.GTIDPosition, targetGTID); err != nil {
+		return fmt.Errorf("snapshot_revert: replaying %s from %s to %s: %w", snapshot.Table, snapshot.GTIDPosition, targetGTID, err)
+	}
+	return nil
+}
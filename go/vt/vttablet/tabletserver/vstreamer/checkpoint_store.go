@@ -0,0 +1,163 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vstreamer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"vitess.io/vitess/go/sqltypes"
+	"vitess.io/vitess/go/vt/dbconfigs"
+	"vitess.io/vitess/go/vt/sqlparser"
+)
+
+// vstreamCheckpointInterval is how often a rowStreamer persists its
+// current lastpk through the configured CheckpointStore, independent of
+// when a data packet happens to be sent. Zero disables periodic
+// checkpointing; Lastpk is then only durable at packet boundaries and
+// end-of-stream, same as before this was added.
+var vstreamCheckpointInterval time.Duration
+
+// CheckpointStore persists the last primary key processed for a table's
+// copy phase, so a fresh rowStreamer can resume from it after a sender
+// crash instead of redoing the whole table.
+type CheckpointStore interface {
+	SaveCheckpoint(ctx context.Context, table string, lastpk []sqltypes.Value) error
+	LoadCheckpoint(ctx context.Context, table string) ([]sqltypes.Value, error)
+}
+
+// inMemoryCheckpointStore is the default CheckpointStore: it survives
+// packet-boundary crashes within the same process but not a restart.
+type inMemoryCheckpointStore struct {
+	mu          sync.Mutex
+	checkpoints map[string][]sqltypes.Value
+}
+
+// defaultCheckpointStore is the process-wide inMemoryCheckpointStore that
+// newRowStreamer installs on every rowStreamer while
+// vstreamCheckpointInterval is positive, unless setCheckpointStore later
+// installs a different one, so a retried rowStreamer for the same table
+// resumes from the last saved lastpk.
+var defaultCheckpointStore = newInMemoryCheckpointStore()
+
+func newInMemoryCheckpointStore() *inMemoryCheckpointStore {
+	return &inMemoryCheckpointStore{checkpoints: map[string][]sqltypes.Value{}}
+}
+
+func (s *inMemoryCheckpointStore) SaveCheckpoint(_ context.Context, table string, lastpk []sqltypes.Value) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checkpoints[table] = append([]sqltypes.Value(nil), lastpk...)
+	return nil
+}
+
+func (s *inMemoryCheckpointStore) LoadCheckpoint(_ context.Context, table string) ([]sqltypes.Value, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.checkpoints[table], nil
+}
+
+// mysqlCheckpointStore persists checkpoints to _vt.copy_progress on the
+// source MySQL instance, so they survive a restart of the vttablet
+// process itself, not just of a single rowStreamer.
+type mysqlCheckpointStore struct {
+	cp     dbconfigs.Connector
+	parser *sqlparser.Parser
+}
+
+// NewMySQLCheckpointStore returns a CheckpointStore backed by the
+// _vt.copy_progress table, reached through cp. Callers are responsible for
+// having created that table, e.g. as part of the usual _vt schema
+// bootstrap:
+//
+//	CREATE TABLE IF NOT EXISTS _vt.copy_progress (
+//	  table_name VARBINARY(128) NOT NULL,
+//	  lastpk VARBINARY(2000) NOT NULL,
+//	  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+//	  PRIMARY KEY (table_name)
+//	)
+func NewMySQLCheckpointStore(cp dbconfigs.Connector, parser *sqlparser.Parser) CheckpointStore {
+	return &mysqlCheckpointStore{cp: cp, parser: parser}
+}
+
+func (s *mysqlCheckpointStore) SaveCheckpoint(ctx context.Context, table string, lastpk []sqltypes.Value) error {
+	conn, err := s.cp.Connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	encoded := encodeLastpk(lastpk)
+	query := fmt.Sprintf(
+		"insert into _vt.copy_progress (table_name, lastpk) values (%s, %s) on duplicate key update lastpk = values(lastpk)",
+		encodeSQLString(table), encodeSQLString(encoded))
+	_, err = conn.ExecuteFetch(query, 1, false)
+	return err
+}
+
+func (s *mysqlCheckpointStore) LoadCheckpoint(ctx context.Context, table string) ([]sqltypes.Value, error) {
+	conn, err := s.cp.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	query := fmt.Sprintf("select lastpk from _vt.copy_progress where table_name = %s", encodeSQLString(table))
+	qr, err := conn.ExecuteFetch(query, 1, false)
+	if err != nil || len(qr.Rows) == 0 {
+		return nil, err
+	}
+	return decodeLastpk(s.parser, qr.Rows[0][0].ToString())
+}
+
+// encodeLastpk/decodeLastpk round-trip a lastpk tuple through the
+// sqlparser tuple literal representation, reusing the same encoding the
+// row streamer already uses to emit lastpk values into its resume query.
+func encodeLastpk(lastpk []sqltypes.Value) string {
+	buf := sqlparser.NewTrackedBuffer(nil)
+	buf.Myprintf("(")
+	for i, v := range lastpk {
+		if i > 0 {
+			buf.Myprintf(", ")
+		}
+		v.EncodeSQL(buf)
+	}
+	buf.Myprintf(")")
+	return buf.String()
+}
+
+func decodeLastpk(parser *sqlparser.Parser, encoded string) ([]sqltypes.Value, error) {
+	expr, err := parser.ParseExpr(encoded)
+	if err != nil {
+		return nil, err
+	}
+	tuple, ok := expr.(sqlparser.ValTuple)
+	if !ok {
+		return nil, fmt.Errorf("unexpected checkpoint format: %s", encoded)
+	}
+	values := make([]sqltypes.Value, 0, len(tuple))
+	for _, e := range tuple {
+		lit, ok := e.(*sqlparser.Literal)
+		if !ok {
+			return nil, fmt.Errorf("unexpected checkpoint literal: %s", sqlparser.String(e))
+		}
+		values = append(values, sqltypes.MakeTrusted(lit.SQLType(), []byte(lit.Val)))
+	}
+	return values, nil
+}
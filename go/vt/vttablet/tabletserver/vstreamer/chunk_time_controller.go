@@ -0,0 +1,144 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vstreamer
+
+import (
+	"sync"
+	"time"
+)
+
+// vstreamChunkTargetDuration is the wall-clock budget each row-copy chunk
+// should target on the source, when chunk-time adaptive sizing is enabled
+// via newChunkTimeController. Zero disables it. Analogous to gh-ost's
+// --chunk-size, but driven by measured execution time instead of a fixed
+// row count.
+var vstreamChunkTargetDuration time.Duration
+
+const (
+	// chunkTimeEWMAWeight is how much a single chunk's duration moves the
+	// running average; lower is smoother, higher reacts faster.
+	chunkTimeEWMAWeight = 0.2
+	// chunkTimeOvershootFactor halves the chunk size once the measured
+	// duration exceeds this multiple of the target.
+	chunkTimeOvershootFactor = 2.0
+	// chunkTimeGrowthFactor is how much the chunk size grows (additively,
+	// as a fraction of itself) each time a chunk comes in under target.
+	chunkTimeGrowthFactor = 0.1
+)
+
+// chunkTimeController wraps a PacketSizer, additionally tracking the
+// duration passed to Record (in streamQuery, the time spent sending a
+// packet; the source-side fetch is not included) and adjusting its own
+// row-count budget so chunks trend toward targetDuration: it halves the
+// budget on a hard overshoot and grows it additively otherwise, the same
+// shape as the EWMA+hard-ceiling feedback loop gh-ost uses for
+// --chunk-size. The wrapped sizer's byte target is left unchanged.
+type chunkTimeController struct {
+	inner          PacketSizer
+	targetDuration time.Duration
+
+	mu         sync.Mutex
+	ewmaMillis float64
+	chunkSize  int
+	// rowsSinceSend counts ShouldSend calls since it last returned true.
+	rowsSinceSend int
+	minChunk      int
+	maxChunk      int
+}
+
+// newChunkTimeController wraps inner so that ShouldSend is additionally
+// gated on a chunk-size counter adapted from measured chunk durations,
+// targeting targetDuration per chunk.
+func newChunkTimeController(inner PacketSizer, targetDuration time.Duration, minChunk, maxChunk int) *chunkTimeController {
+	if minChunk <= 0 {
+		minChunk = 1
+	}
+	if maxChunk < minChunk {
+		maxChunk = minChunk
+	}
+	return &chunkTimeController{
+		inner:          inner,
+		targetDuration: targetDuration,
+		chunkSize:      maxChunk,
+		minChunk:       minChunk,
+		maxChunk:       maxChunk,
+	}
+}
+
+// ShouldSend defers to the wrapped PacketSizer for the byte-based decision,
+// but also forces a send once the adapted row-count budget is exhausted so
+// that chunk duration can be measured and fed back on the next Record.
+// Each call counts as one row, since streamQuery calls it once per fetched
+// row.
+func (c *chunkTimeController) ShouldSend(byteCount int) bool {
+	send := c.inner.ShouldSend(byteCount)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rowsSinceSend++
+	if c.targetDuration > 0 && c.rowsSinceSend >= c.chunkSize {
+		send = true
+	}
+	if send {
+		c.rowsSinceSend = 0
+	}
+	return send
+}
+
+// Record feeds sendLatency into the EWMA as the chunk duration and adjusts the
+// target chunk size before delegating byte/latency bookkeeping to the
+// wrapped PacketSizer.
+func (c *chunkTimeController) Record(byteCount int, sendLatency time.Duration) {
+	c.observe(sendLatency)
+	c.inner.Record(byteCount, sendLatency)
+}
+
+// observe updates the EWMA of chunk duration and resizes the target chunk
+// count: halve on a hard overshoot past chunkTimeOvershootFactor*target,
+// otherwise grow additively toward maxChunk while under target.
+func (c *chunkTimeController) observe(d time.Duration) {
+	if c.targetDuration <= 0 {
+		return
+	}
+	millis := float64(d.Milliseconds())
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.ewmaMillis == 0 {
+		c.ewmaMillis = millis
+	} else {
+		c.ewmaMillis = chunkTimeEWMAWeight*millis + (1-chunkTimeEWMAWeight)*c.ewmaMillis
+	}
+
+	targetMillis := float64(c.targetDuration.Milliseconds())
+	switch {
+	case c.ewmaMillis > targetMillis*chunkTimeOvershootFactor:
+		c.chunkSize = max(c.minChunk, c.chunkSize/2)
+	case c.ewmaMillis < targetMillis:
+		grown := c.chunkSize + int(float64(c.chunkSize)*chunkTimeGrowthFactor) + 1
+		c.chunkSize = min(c.maxChunk, grown)
+	}
+}
+
+// currentChunkSize returns the row-count budget the controller currently
+// recommends for the next chunk, for callers that iterate with explicit
+// LIMIT/lastpk resumption rather than relying on ShouldSend alone.
+func (c *chunkTimeController) currentChunkSize() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.chunkSize
+}
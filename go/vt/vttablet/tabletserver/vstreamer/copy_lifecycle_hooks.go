@@ -0,0 +1,115 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vstreamer
+
+import (
+	"time"
+
+	"vitess.io/vitess/go/sqltypes"
+	"vitess.io/vitess/go/vt/log"
+)
+
+// copyHookTimeout is how long a single lifecycle hook invocation may run
+// before fireHook logs a warning about it; the hook keeps running, and
+// hooks never block the stream.
+const copyHookTimeout = 2 * time.Second
+
+// CopyLifecycleHooks are user-supplied callbacks invoked at well-defined
+// points of a rowStreamer's copy phase, mirroring the operational
+// integration points gh-ost exposes for its cut-over/throttling events.
+// Every callback is optional and invoked fire-and-forget: a slow or
+// panicking hook is logged and abandoned, never allowed to stall the
+// stream itself.
+type CopyLifecycleHooks struct {
+	// OnCopyStart fires once the copy phase's consistent-snapshot GTID has
+	// been established, before any rows are fetched.
+	OnCopyStart func(table, keyspace, shard, gtid string)
+	// OnCopyChunk fires after each packet of rows is successfully sent.
+	OnCopyChunk func(table string, rowsSent int, lastpk []sqltypes.Value)
+	// OnCopyThrottled fires the first time the throttler blocks progress,
+	// and is not fired again until OnCopyResumed has fired.
+	OnCopyThrottled func(table, reason string)
+	// OnCopyResumed fires once progress resumes after a throttled period.
+	OnCopyResumed func(table string)
+	// OnCopyComplete fires once the table's copy phase has finished.
+	OnCopyComplete func(table string, totalRows int64, duration time.Duration)
+}
+
+// fireHook runs fn in its own goroutine and never waits on it: the caller
+// returns immediately. copyHookTimeout is advisory only, used to log a
+// warning if the hook is still running after it elapses - it does not
+// delay the caller, which would defeat the entire point of firing the hook
+// asynchronously in the first place. The goroutine is not forcibly killed -
+// Go has no mechanism for that - it just keeps running in the background,
+// logged about but otherwise ignored, until it returns or panics.
+func fireHook(name string, fn func()) {
+	if fn == nil {
+		return
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer func() {
+			if r := recover(); r != nil {
+				log.Errorf("vstreamer: copy lifecycle hook %s panicked: %v", name, r)
+			}
+		}()
+		fn()
+	}()
+	go func() {
+		select {
+		case <-done:
+		case <-time.After(copyHookTimeout):
+			log.Warningf("vstreamer: copy lifecycle hook %s did not return within %s, abandoning it", name, copyHookTimeout)
+		}
+	}()
+}
+
+func (h *CopyLifecycleHooks) fireOnCopyStart(table, keyspace, shard, gtid string) {
+	if h == nil || h.OnCopyStart == nil {
+		return
+	}
+	fireHook("OnCopyStart", func() { h.OnCopyStart(table, keyspace, shard, gtid) })
+}
+
+func (h *CopyLifecycleHooks) fireOnCopyChunk(table string, rowsSent int, lastpk []sqltypes.Value) {
+	if h == nil || h.OnCopyChunk == nil {
+		return
+	}
+	fireHook("OnCopyChunk", func() { h.OnCopyChunk(table, rowsSent, lastpk) })
+}
+
+func (h *CopyLifecycleHooks) fireOnCopyThrottled(table, reason string) {
+	if h == nil || h.OnCopyThrottled == nil {
+		return
+	}
+	fireHook("OnCopyThrottled", func() { h.OnCopyThrottled(table, reason) })
+}
+
+func (h *CopyLifecycleHooks) fireOnCopyResumed(table string) {
+	if h == nil || h.OnCopyResumed == nil {
+		return
+	}
+	fireHook("OnCopyResumed", func() { h.OnCopyResumed(table) })
+}
+
+func (h *CopyLifecycleHooks) fireOnCopyComplete(table string, totalRows int64, duration time.Duration) {
+	if h == nil || h.OnCopyComplete == nil {
+		return
+	}
+	fireHook("OnCopyComplete", func() { h.OnCopyComplete(table, totalRows, duration) })
+}
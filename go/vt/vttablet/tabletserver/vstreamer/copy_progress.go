@@ -0,0 +1,156 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vstreamer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"vitess.io/vitess/go/stats"
+	"vitess.io/vitess/go/vt/dbconfigs"
+)
+
+// copyProgressEWMAWeight is how much each packet's observed rows/sec moves
+// the running throughput estimate used for the ETA.
+const copyProgressEWMAWeight = 0.3
+
+var (
+	rowStreamerETASeconds = stats.NewGaugesWithSingleLabel(
+		"RowStreamerETASeconds",
+		"Estimated seconds remaining in the row streamer copy phase, by table",
+		"Table")
+	rowStreamerPercentComplete = stats.NewGaugesWithSingleLabel(
+		"RowStreamerPercentComplete",
+		"Percent of estimated total rows copied so far by the row streamer, by table",
+		"Table")
+)
+
+// copyProgressTracker estimates how far a table's copy phase has gotten and
+// how long it has left, using an exponentially-weighted moving average of
+// rows/sec observed across recently sent packets. It gives operators the
+// same "45% done, ETA 12m" visibility gh-ost provides during long copy
+// phases.
+type copyProgressTracker struct {
+	table          string
+	estimatedTotal int64
+
+	mu          sync.Mutex
+	rowsCopied  int64
+	rowsPerSec  float64
+	lastObserve time.Time
+}
+
+// newCopyProgressTracker creates a tracker for table, sampling
+// information_schema.TABLES for an approximate row count to use as the
+// denominator for percent-complete. A failure to estimate is non-fatal:
+// the tracker then reports 0% and an unknown ETA for the whole copy.
+func newCopyProgressTracker(ctx context.Context, cp dbconfigs.Connector, table string) *copyProgressTracker {
+	t := &copyProgressTracker{table: table}
+	t.estimatedTotal = estimateTableRows(ctx, cp, table)
+	return t
+}
+
+func estimateTableRows(ctx context.Context, cp dbconfigs.Connector, table string) int64 {
+	conn, err := cp.Connect(ctx)
+	if err != nil {
+		return 0
+	}
+	defer conn.Close()
+
+	query := fmt.Sprintf("select table_rows from information_schema.tables where table_schema = database() and table_name = %s",
+		encodeSQLString(table))
+	qr, err := conn.ExecuteFetch(query, 1, false)
+	if err != nil || len(qr.Rows) == 0 {
+		return 0
+	}
+	n, err := qr.Rows[0][0].ToCastInt64()
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+func encodeSQLString(s string) string {
+	return "'" + s + "'"
+}
+
+// observe records that rowsSent additional rows were sent in the most
+// recent packet, updates the rows/sec EWMA, and refreshes the exported
+// stats vars for this table.
+func (t *copyProgressTracker) observe(rowsSent int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	t.rowsCopied += int64(rowsSent)
+
+	if !t.lastObserve.IsZero() {
+		elapsed := now.Sub(t.lastObserve).Seconds()
+		if elapsed > 0 {
+			instantRate := float64(rowsSent) / elapsed
+			if t.rowsPerSec == 0 {
+				t.rowsPerSec = instantRate
+			} else {
+				t.rowsPerSec = copyProgressEWMAWeight*instantRate + (1-copyProgressEWMAWeight)*t.rowsPerSec
+			}
+		}
+	}
+	t.lastObserve = now
+
+	percent, etaSeconds := t.snapshotLocked()
+	rowStreamerPercentComplete.Set(t.table, int64(percent))
+	if etaSeconds >= 0 {
+		rowStreamerETASeconds.Set(t.table, etaSeconds)
+	}
+}
+
+// snapshotLocked computes percent-complete (0-100) and an ETA in seconds
+// (-1 if it cannot be estimated yet). Callers must hold t.mu.
+func (t *copyProgressTracker) snapshotLocked() (percent float64, etaSeconds int64) {
+	if t.estimatedTotal <= 0 {
+		return 0, -1
+	}
+	percent = 100 * float64(t.rowsCopied) / float64(t.estimatedTotal)
+	if percent > 100 {
+		percent = 100
+	}
+	if t.rowsPerSec <= 0 {
+		return percent, -1
+	}
+	remaining := float64(t.estimatedTotal) - float64(t.rowsCopied)
+	if remaining < 0 {
+		remaining = 0
+	}
+	return percent, int64(remaining / t.rowsPerSec)
+}
+
+// snapshot returns the same values as snapshotLocked but takes the lock
+// itself, for callers outside the tracker (e.g. tests).
+func (t *copyProgressTracker) snapshot() (percent float64, etaSeconds int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.snapshotLocked()
+}
+
+// totalRowsCopied returns the running count of rows sent so far.
+func (t *copyProgressTracker) totalRowsCopied() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.rowsCopied
+}
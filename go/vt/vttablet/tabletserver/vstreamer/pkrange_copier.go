@@ -0,0 +1,158 @@
+/*
+Copyright 2024 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vstreamer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"vitess.io/vitess/go/sqltypes"
+	"vitess.io/vitess/go/vt/dbconfigs"
+	"vitess.io/vitess/go/vt/log"
+)
+
+// pkRange is a half-open primary-key range (lower, upper] assigned to one
+// shard of a parallel copy. A nil lower bound means "from the start of the
+// table"; a nil upper bound means "through the end of the table".
+type pkRange struct {
+	lower []sqltypes.Value
+	upper []sqltypes.Value
+}
+
+// planPKRanges picks numShards-1 interior boundaries for st's primary key,
+// producing numShards disjoint pkRanges that together cover the whole
+// table. Boundaries are chosen by sampling the table with
+// `ORDER BY pk LIMIT 1 OFFSET k*rows/numShards`, which works for any PK
+// shape (not just single integer columns) at the cost of one extra query
+// per interior boundary.
+func planPKRanges(ctx context.Context, cp dbconfigs.Connector, rs *rowStreamer, numShards int) ([]pkRange, error) {
+	if numShards < 2 {
+		return []pkRange{{}}, nil
+	}
+
+	conn, err := cp.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	countQuery := fmt.Sprintf("select count(*) from %s", sqlescapeIdent(rs.plan.Table.Name))
+	qr, err := conn.ExecuteFetch(countQuery, 1, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(qr.Rows) == 0 {
+		return []pkRange{{}}, nil
+	}
+	total, err := qr.Rows[0][0].ToCastInt64()
+	if err != nil {
+		return nil, err
+	}
+	if total <= 0 {
+		return []pkRange{{}}, nil
+	}
+
+	pkColList := ""
+	for i, pk := range rs.pkColumns {
+		if i > 0 {
+			pkColList += ", "
+		}
+		pkColList += sqlescapeIdent(rs.plan.Table.Fields[pk].Name)
+	}
+
+	boundaries := make([][]sqltypes.Value, 0, numShards-1)
+	for k := 1; k < numShards; k++ {
+		offset := total * int64(k) / int64(numShards)
+		query := fmt.Sprintf("select %s from %s order by %s limit 1 offset %d",
+			pkColList, sqlescapeIdent(rs.plan.Table.Name), pkColList, offset)
+		qr, err := conn.ExecuteFetch(query, 1, false)
+		if err != nil {
+			return nil, err
+		}
+		if len(qr.Rows) == 0 {
+			// The table shrank since it was counted; stop handing out boundaries.
+			break
+		}
+		boundaries = append(boundaries, qr.Rows[0])
+	}
+
+	ranges := make([]pkRange, 0, len(boundaries)+1)
+	var lower []sqltypes.Value
+	for _, boundary := range boundaries {
+		ranges = append(ranges, pkRange{lower: lower, upper: boundary})
+		lower = boundary
+	}
+	ranges = append(ranges, pkRange{lower: lower})
+	return ranges, nil
+}
+
+func sqlescapeIdent(name string) string {
+	return "`" + name + "`"
+}
+
+// parallelRowStreamerCopy splits base's table copy across numShards
+// concurrent rowStreamer instances, one per disjoint PK range returned by
+// planPKRanges. Each shard opens its own consistent snapshot connection
+// when its Stream starts, so the shards' snapshots, and the GTIDs they
+// report, can differ. base is used as the template (plan, query, send,
+// vschema, ...); it is never streamed from directly in this mode. lastpk
+// reporting stays per-shard, so resuming after a crash only redoes the
+// shard that failed rather than the whole table.
+func parallelRowStreamerCopy(ctx context.Context, base *rowStreamer, numShards int) error {
+	if err := base.se.Open(); err != nil {
+		return err
+	}
+	if err := base.buildPlan(); err != nil {
+		return err
+	}
+
+	ranges, err := planPKRanges(ctx, base.cp, base, numShards)
+	if err != nil {
+		return err
+	}
+	log.Infof("parallel row copy for %s: %d shard(s)", base.plan.Table.Name, len(ranges))
+
+	var (
+		wg       sync.WaitGroup
+		errMu    sync.Mutex
+		firstErr error
+	)
+	for _, r := range ranges {
+		r := r
+		shard := newRowStreamer(ctx, base.cp, base.se, base.query, r.lower, base.vschema, base.send, base.vse,
+			RowStreamerModeSingleTable, nil, base.options)
+		if shard == nil {
+			return fmt.Errorf("failed to build shard row streamer for %s", base.plan.Table.Name)
+		}
+		shard.setPKRange(r.lower, r.upper)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := shard.Stream(); err != nil {
+				errMu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				errMu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return firstErr
+}
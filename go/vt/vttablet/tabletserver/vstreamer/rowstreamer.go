@@ -51,6 +51,10 @@ type RowStreamerMode int32
 const (
 	RowStreamerModeSingleTable RowStreamerMode = iota
 	RowStreamerModeAllTables
+	// RowStreamerModeParallel splits a single table's copy phase across
+	// multiple concurrent rowStreamer instances, each bounded to a disjoint
+	// primary-key range. See pkrange_copier.go.
+	RowStreamerModeParallel
 )
 
 // rowStreamer is used for copying the existing rows of a table
@@ -65,10 +69,14 @@ type rowStreamer struct {
 	ctx    context.Context
 	cancel func()
 
-	cp      dbconfigs.Connector
-	se      *schema.Engine
-	query   string
-	lastpk  []sqltypes.Value
+	cp     dbconfigs.Connector
+	se     *schema.Engine
+	query  string
+	lastpk []sqltypes.Value
+	// upperpk bounds this streamer's copy to the PK range (lastpk, upperpk],
+	// used when the table's copy phase is split across multiple concurrent
+	// rowStreamer instances by pkrange_copier.go. Empty means unbounded.
+	upperpk []sqltypes.Value
 	send    func(*binlogdatapb.VStreamRowsResponse) error
 	vschema *localVSchema
 
@@ -83,6 +91,45 @@ type rowStreamer struct {
 	conn    *snapshotConn
 	options *binlogdatapb.VStreamOptions
 	config  *vttablet.VReplicationConfig
+
+	// progress tracks rows-copied/percent-complete/ETA for this table's
+	// copy phase. Lazily created in streamQuery, since it needs the plan
+	// (and therefore the table name) to have been built first.
+	progress *copyProgressTracker
+
+	// hooks, if set, are invoked at well-defined points of the copy phase.
+	// Set via setLifecycleHooks before calling Stream.
+	hooks *CopyLifecycleHooks
+
+	// checkpointStore, if set, persists lastpk on vstreamCheckpointInterval
+	// and is consulted to seed rs.lastpk when the caller passed nil.
+	checkpointStore CheckpointStore
+
+	// numParallelShards is the number of concurrent rowStreamer instances
+	// to split this table's copy phase across when mode is
+	// RowStreamerModeParallel. Only meaningful in that mode; see
+	// setParallelShards and pkrange_copier.go.
+	numParallelShards int
+}
+
+// setParallelShards sets the number of PK-range shards Stream splits this
+// table's copy phase across when mode is RowStreamerModeParallel. Values
+// less than 2 are treated as a single, unsplit shard.
+func (rs *rowStreamer) setParallelShards(n int) {
+	rs.numParallelShards = n
+}
+
+// setLifecycleHooks installs the lifecycle callbacks fired during Stream.
+func (rs *rowStreamer) setLifecycleHooks(hooks *CopyLifecycleHooks) {
+	rs.hooks = hooks
+}
+
+// setCheckpointStore installs store as the durable home for this
+// streamer's lastpk. If the caller didn't already provide a lastpk,
+// buildPlan consults store once the table name is known, to resume from
+// where a previous, crashed rowStreamer for this table left off.
+func (rs *rowStreamer) setCheckpointStore(store CheckpointStore) {
+	rs.checkpointStore = store
 }
 
 func newRowStreamer(ctx context.Context, cp dbconfigs.Connector, se *schema.Engine, query string,
@@ -94,7 +141,11 @@ func newRowStreamer(ctx context.Context, cp dbconfigs.Connector, se *schema.Engi
 		return nil
 	}
 	ctx, cancel := context.WithCancel(ctx)
-	return &rowStreamer{
+	pktsize := DefaultPacketSizer(config.VStreamDynamicPacketSize, config.VStreamPacketSize)
+	if vstreamChunkTargetDuration > 0 {
+		pktsize = newChunkTimeController(pktsize, vstreamChunkTargetDuration, 1, config.VStreamPacketSize)
+	}
+	rs := &rowStreamer{
 		ctx:     ctx,
 		cancel:  cancel,
 		cp:      cp,
@@ -104,12 +155,45 @@ func newRowStreamer(ctx context.Context, cp dbconfigs.Connector, se *schema.Engi
 		send:    send,
 		vschema: vschema,
 		vse:     vse,
-		pktsize: DefaultPacketSizer(config.VStreamDynamicPacketSize, config.VStreamPacketSize),
+		pktsize: pktsize,
 		mode:    mode,
 		conn:    conn,
 		options: options,
 		config:  config,
 	}
+	if vstreamCheckpointInterval > 0 {
+		// Default to the process-wide in-memory store so a fresh
+		// rowStreamer for the same table (e.g. one retrying after the
+		// previous one's connection dropped) still resumes from the last
+		// saved lastpk; setCheckpointStore lets a caller swap in a
+		// durable one (e.g. NewMySQLCheckpointStore) that also survives a
+		// vttablet restart, not just a rowStreamer retry.
+		rs.checkpointStore = defaultCheckpointStore
+	}
+	return rs
+}
+
+// setPKRange bounds this streamer's copy to the half-open PK range
+// (lower, upper]. Either bound may be nil to leave that side unbounded.
+// Only used by pkrange_copier.go to assign disjoint shards of a table's
+// copy phase to concurrent rowStreamer instances.
+func (rs *rowStreamer) setPKRange(lower, upper []sqltypes.Value) {
+	rs.lastpk = lower
+	rs.upperpk = upper
+}
+
+// checkpointKey returns the key this streamer uses to save/load its
+// checkpoint. A streamer covering a table's whole copy phase uses the
+// table name alone, same as before shards existed. A streamer assigned
+// only a bounded shard of the table via setPKRange folds its upper bound
+// into the key too, so the concurrent shards parallelRowStreamerCopy
+// starts for the same table persist to distinct checkpoints instead of
+// racing to overwrite one shared, table-only key.
+func (rs *rowStreamer) checkpointKey(table string) string {
+	if len(rs.upperpk) == 0 {
+		return table
+	}
+	return table + "|" + encodeLastpk(rs.upperpk)
 }
 
 func (rs *rowStreamer) Cancel() {
@@ -118,6 +202,14 @@ func (rs *rowStreamer) Cancel() {
 }
 
 func (rs *rowStreamer) Stream() error {
+	if rs.mode == RowStreamerModeParallel {
+		shards := rs.numParallelShards
+		if shards < 1 {
+			shards = 1
+		}
+		return parallelRowStreamerCopy(rs.ctx, rs, shards)
+	}
+
 	// Ensure se is Open. If vttablet came up in a non_serving role,
 	// the schema engine may not have been initialized.
 	if err := rs.se.Open(); err != nil {
@@ -194,6 +286,11 @@ func (rs *rowStreamer) buildPlan() error {
 	if err != nil {
 		return err
 	}
+	if len(rs.lastpk) == 0 && rs.checkpointStore != nil {
+		if lastpk, err := rs.checkpointStore.LoadCheckpoint(rs.ctx, rs.checkpointKey(st.Name)); err == nil && len(lastpk) != 0 {
+			rs.lastpk = lastpk
+		}
+	}
 	rs.sendQuery, err = rs.buildSelect(st)
 	if err != nil {
 		return err
@@ -320,6 +417,38 @@ func (rs *rowStreamer) buildSelect(st *binlogdatapb.MinimalTable) (string, error
 		buf.Myprintf(" where ")
 		addPushdownExpressions()
 	}
+	if len(rs.upperpk) != 0 { // This streamer only owns a bounded shard of the PK range.
+		if len(rs.upperpk) != len(rs.pkColumns) {
+			return "", fmt.Errorf("cannot build a row streamer plan for the %s table as an upperpk value was provided and the number of primary key values within it (%v) does not match the number of primary key columns in the table (%d)",
+				st.Name, rs.upperpk, rs.pkColumns)
+		}
+		if len(rs.lastpk) == 0 && len(rs.plan.whereExprsToPushDown) == 0 {
+			buf.WriteString(" where ")
+		} else {
+			buf.Myprintf(" and ")
+		}
+		// Same expansion as the lower bound above, so a composite PK is bounded
+		// by tuple order: (col1 = 1 and col2 <= 2) or (col1 < 1).
+		buf.Myprintf("(")
+		prefix := ""
+		for lastcol := len(rs.pkColumns) - 1; lastcol >= 0; lastcol-- {
+			buf.Myprintf("%s(", prefix)
+			prefix = " or "
+			for i, pk := range rs.pkColumns[:lastcol] {
+				buf.Myprintf("%v = ", sqlparser.NewIdentifierCI(rs.plan.Table.Fields[pk].Name))
+				rs.upperpk[i].EncodeSQL(buf)
+				buf.Myprintf(" and ")
+			}
+			op := "<"
+			if lastcol == len(rs.pkColumns)-1 {
+				op = "<="
+			}
+			buf.Myprintf("%v %s ", sqlparser.NewIdentifierCI(rs.plan.Table.Fields[rs.pkColumns[lastcol]].Name), op)
+			rs.upperpk[lastcol].EncodeSQL(buf)
+			buf.Myprintf(")")
+		}
+		buf.Myprintf(")")
+	}
 	buf.Myprintf(" order by ", sqlparser.NewIdentifierCS(rs.plan.Table.Name))
 	prefix = ""
 	for _, pk := range rs.pkColumns {
@@ -343,6 +472,7 @@ func (rs *rowStreamer) streamQuery(send func(*binlogdatapb.VStreamRowsResponse)
 	if err := rs.vse.waitForMySQL(rs.ctx, rs.cp, rs.plan.Table.Name); err != nil {
 		return err
 	}
+	rs.progress = newCopyProgressTracker(rs.ctx, rs.cp, rs.plan.Table.Name)
 	var (
 		gtid       string
 		rotatedLog bool
@@ -379,6 +509,9 @@ func (rs *rowStreamer) streamQuery(send func(*binlogdatapb.VStreamRowsResponse)
 		charsets[i] = collations.ID(fld.Charset)
 	}
 
+	rs.hooks.fireOnCopyStart(rs.plan.Table.Name, rs.cp.DBName(), "", gtid)
+	copyStart := time.Now()
+
 	err = safeSend(&binlogdatapb.VStreamRowsResponse{
 		Fields:   rs.plan.fields(),
 		Pkfields: pkfields,
@@ -409,8 +542,53 @@ func (rs *rowStreamer) streamQuery(send func(*binlogdatapb.VStreamRowsResponse)
 		mysqlrow []sqltypes.Value
 	)
 
+	var lastpkMu sync.Mutex
+	// checkpointReady is true while every row up to lastpk has been sent or
+	// filtered out; guarded by lastpkMu.
+	checkpointReady := false
 	lastpk := make([]sqltypes.Value, len(rs.pkColumns))
+
+	// Persist lastpk on a fixed interval, independent of when a data
+	// packet happens to fill up: otherwise a client crash between packets
+	// can force redoing every row scanned and filtered out since the last
+	// packet. A checkpoint is only taken while no fetched row is waiting in
+	// an unsent packet. The response carries Lastpk with Checkpoint set and
+	// no Rows, so the client can tell it apart from a normal data packet.
+	if vstreamCheckpointInterval > 0 {
+		checkpointTicker := time.NewTicker(vstreamCheckpointInterval)
+		defer checkpointTicker.Stop()
+		go func() {
+			for {
+				select {
+				case <-rs.ctx.Done():
+					return
+				case <-checkpointTicker.C:
+					lastpkMu.Lock()
+					pk := append([]sqltypes.Value(nil), lastpk...)
+					ready := checkpointReady
+					lastpkMu.Unlock()
+					if len(pk) == 0 || !ready {
+						continue
+					}
+					if err := safeSend(&binlogdatapb.VStreamRowsResponse{
+						Lastpk:     sqltypes.RowToProto3(pk),
+						Checkpoint: true,
+					}); err != nil {
+						log.Warningf("vstreamer: failed to send periodic checkpoint for %s: %v", rs.plan.Table.Name, err)
+					}
+					if rs.checkpointStore != nil {
+						key := rs.checkpointKey(rs.plan.Table.Name)
+						if err := rs.checkpointStore.SaveCheckpoint(rs.ctx, key, pk); err != nil {
+							log.Warningf("vstreamer: failed to persist checkpoint for %s: %v", key, err)
+						}
+					}
+				}
+			}
+		}()
+	}
+
 	byteCount := 0
+	wasThrottled := false
 	logger := logutil.NewThrottledLogger(rs.vse.GetTabletInfo(), throttledLoggerInterval)
 	for {
 		if rs.ctx.Err() != nil {
@@ -423,9 +601,17 @@ func (rs *rowStreamer) streamQuery(send func(*binlogdatapb.VStreamRowsResponse)
 			throttleResponseRateLimiter.Do(func() error {
 				return safeSend(&binlogdatapb.VStreamRowsResponse{Throttled: true, ThrottledReason: checkResult.Summary()})
 			})
+			if !wasThrottled {
+				rs.hooks.fireOnCopyThrottled(rs.plan.Table.Name, checkResult.Summary())
+				wasThrottled = true
+			}
 			logger.Infof("throttled.")
 			continue
 		}
+		if wasThrottled {
+			rs.hooks.fireOnCopyResumed(rs.plan.Table.Name)
+			wasThrottled = false
+		}
 
 		if mysqlrow != nil {
 			mysqlrow = mysqlrow[:0]
@@ -439,9 +625,12 @@ func (rs *rowStreamer) streamQuery(send func(*binlogdatapb.VStreamRowsResponse)
 		}
 		// Compute lastpk here, because we'll need it
 		// at the end after the loop exits.
+		lastpkMu.Lock()
 		for i, pk := range rs.pkColumns {
 			lastpk[i] = mysqlrow[pk]
 		}
+		checkpointReady = false
+		lastpkMu.Unlock()
 
 		// verify that the row should be sent
 		ok, _, err := rs.plan.shouldFilter(mysqlrow, charsets)
@@ -472,9 +661,14 @@ func (rs *rowStreamer) streamQuery(send func(*binlogdatapb.VStreamRowsResponse)
 				return err
 			}
 			rs.pktsize.Record(byteCount, time.Since(startSend))
+			rs.progress.observe(len(response.Rows))
+			rs.hooks.fireOnCopyChunk(rs.plan.Table.Name, len(response.Rows), lastpk)
 			rowCount = 0
 			byteCount = 0
 		}
+		lastpkMu.Lock()
+		checkpointReady = rowCount == 0
+		lastpkMu.Unlock()
 	}
 
 	if rowCount > 0 {
@@ -486,8 +680,11 @@ func (rs *rowStreamer) streamQuery(send func(*binlogdatapb.VStreamRowsResponse)
 		if err != nil {
 			return err
 		}
+		rs.progress.observe(len(response.Rows))
+		rs.hooks.fireOnCopyChunk(rs.plan.Table.Name, len(response.Rows), lastpk)
 	}
 
+	rs.hooks.fireOnCopyComplete(rs.plan.Table.Name, rs.progress.totalRowsCopied(), time.Since(copyStart))
 	return nil
 }
 